@@ -48,6 +48,7 @@ type Token struct {
 	FloatValue float64
 	IntValue   int64
 	IsInteger  bool
+	IsKey      bool   // 由 Decoder.Token 返回的对象键
 	Value      []byte // 字符串值 / 数字原始字节（合并原 RawNumber，省 24B slice header）
 	Pos        int
 }
@@ -59,6 +60,19 @@ type Lexer struct {
 	pos      int
 	start    int
 	width    int
+
+	// 流式模式（r 非 nil）：input 是从 r 读入的滑动窗口，窗口之前已丢弃 offset 字节，
+	// rerr 记录 r 返回的第一个错误（包括 io.EOF）
+	r      io.Reader
+	rerr   error
+	offset int64
+
+	// depth 为流式模式下当前的嵌套层数；stopAtValueEnd 为 true 时，
+	// 回到 valueDepth 层的值结束后置 valueDone，之后 NextToken 返回 EOFToken 而不再读取 r
+	depth          int
+	valueDepth     int
+	stopAtValueEnd bool
+	valueDone      bool
 }
 
 // 用于复用 bytes.Buffer
@@ -73,16 +87,11 @@ func NewLexer(input []byte) *Lexer {
 	return &Lexer{input: input, inputLen: len(input)}
 }
 
-// NewLexerFromReader 从io.Reader创建一个新的词法分析器
+// NewLexerFromReader 从io.Reader创建一个新的词法分析器。
+// 输入按需分块读入滑动窗口，不会一次性读完 r；读取错误在词法分析过程中以 EOFToken 结束，
+// 错误本身不会通过这里返回
 func NewLexerFromReader(r io.Reader) (*Lexer, error) {
-	// 直接读取所有数据到字节切片，避免中间的Buffer拷贝
-	data, err := io.ReadAll(r)
-	if err != nil {
-		return nil, err
-	}
-
-	// 直接创建词法分析器，无需额外拷贝
-	return NewLexer(data), nil
+	return newStreamLexer(r), nil
 }
 
 // Reset 重置词法分析器状态，用于复用
@@ -92,6 +101,8 @@ func (l *Lexer) Reset(input []byte) {
 	l.pos = 0
 	l.start = 0
 	l.width = 0
+	l.r, l.rerr, l.offset = nil, nil, 0
+	l.depth, l.valueDepth, l.stopAtValueEnd, l.valueDone = 0, 0, false, false
 }
 
 // next 返回下一个字符并前进
@@ -114,6 +125,14 @@ func (l *Lexer) ignore() {
 
 // NextToken 返回下一个标记
 func (l *Lexer) NextToken() Token {
+	if l.r != nil {
+		return l.nextTokenStream()
+	}
+	return l.lexToken()
+}
+
+// lexToken 从缓冲区中解析下一个标记
+func (l *Lexer) lexToken() Token {
 	l.start = l.pos
 	inputLen := l.inputLen
 	// 快速跳过空白字符（8字节批量处理）
@@ -241,7 +260,7 @@ func (l *Lexer) lexString() Token {
 		}
 		// 检查无效字符（控制字符）
 		if c < 0x20 {
-			return Token{Type: InvalidToken, Value: []byte("字符串中包含无效控制字符"), Pos: l.pos}
+			return Token{Type: InvalidToken, Value: []byte("字符串中包含无效控制字符"), Pos: startPos}
 		}
 		l.pos++
 	}
@@ -298,13 +317,13 @@ func (l *Lexer) lexStringEscape(startPos, contentStart int) Token {
 			case 'u':
 				// Unicode转义处理
 				if l.pos+4 > inputLen {
-					return Token{Type: InvalidToken, Value: []byte("无效的 Unicode 转义序列 (过短)"), Pos: l.pos - 1}
+					return Token{Type: InvalidToken, Value: []byte("无效的 Unicode 转义序列 (过短)"), Pos: startPos}
 				}
 
 				hex := l.input[l.pos : l.pos+4]
 				code, _, err := parseIntFromBytes(hex, 16, 32)
 				if err != nil {
-					return Token{Type: InvalidToken, Value: []byte("无效的 Unicode 转义序列"), Pos: l.pos - 1}
+					return Token{Type: InvalidToken, Value: []byte("无效的 Unicode 转义序列"), Pos: startPos}
 				}
 				l.pos += 4
 
@@ -336,7 +355,7 @@ func (l *Lexer) lexStringEscape(startPos, contentStart int) Token {
 
 				buf.WriteRune(rune(code))
 			default:
-				return Token{Type: InvalidToken, Value: []byte("无效的转义字符"), Pos: l.pos - 2}
+				return Token{Type: InvalidToken, Value: []byte("无效的转义字符"), Pos: startPos}
 			}
 		} else if c == '"' {
 			l.pos++ // 跳过结束引号
@@ -344,7 +363,7 @@ func (l *Lexer) lexStringEscape(startPos, contentStart int) Token {
 			result := append([]byte(nil), buf.Bytes()...)
 			return Token{Type: StringToken, Value: result, Pos: startPos}
 		} else if c < 0x20 {
-			return Token{Type: InvalidToken, Value: []byte("字符串中包含无效控制字符"), Pos: l.pos}
+			return Token{Type: InvalidToken, Value: []byte("字符串中包含无效控制字符"), Pos: startPos}
 		} else {
 			// 普通字符，写入buffer
 			buf.WriteByte(c)
@@ -0,0 +1,235 @@
+package sjson
+
+import "io"
+
+// streamBufferSize 流式词法分析器的初始窗口大小；单个标记超过窗口时按倍数扩容
+const streamBufferSize = 4096
+
+// maxEmptyReads r 连续返回 (0, nil) 的最大次数，超过后视为 io.ErrNoProgress（与 bufio 一致）
+const maxEmptyReads = 100
+
+// newStreamLexer 创建从 r 按需读取的词法分析器
+func newStreamLexer(r io.Reader) *Lexer {
+	return &Lexer{input: make([]byte, 0, streamBufferSize), r: r}
+}
+
+// fill 从 r 读取更多数据追加到窗口末尾，返回是否读到了新数据。
+// 读取前丢弃 start 之前已消费的字节，pos 与 start 随之前移，
+// 因此调用方只能通过相对 start 的偏移量跨越 fill 保存位置。
+func (l *Lexer) fill() bool {
+	if l.r == nil || l.rerr != nil {
+		return false
+	}
+
+	if l.start > 0 {
+		n := copy(l.input, l.input[l.start:])
+		l.input = l.input[:n]
+		l.offset += int64(l.start)
+		l.pos -= l.start
+		l.start = 0
+	}
+	if len(l.input) == cap(l.input) {
+		buf := make([]byte, len(l.input), 2*cap(l.input)+streamBufferSize)
+		copy(buf, l.input)
+		l.input = buf
+	}
+
+	for i := 0; i < maxEmptyReads; i++ {
+		n, err := l.r.Read(l.input[len(l.input):cap(l.input)])
+		l.input = l.input[:len(l.input)+n]
+		l.inputLen = len(l.input)
+		if err != nil {
+			l.rerr = err
+			return n > 0
+		}
+		if n > 0 {
+			return true
+		}
+	}
+	l.rerr = io.ErrNoProgress
+	return false
+}
+
+// readErr 返回 r 的读取错误；r 已正常读完时返回 io.EOF
+func (l *Lexer) readErr() error {
+	if l.rerr == nil {
+		return io.EOF
+	}
+	return l.rerr
+}
+
+// nextTokenStream 流式模式下的 NextToken：先保证下一个标记完整地位于窗口内，
+// 再交给 lexToken 按内存输入解析
+func (l *Lexer) nextTokenStream() Token {
+	if l.valueDone {
+		return Token{Type: EOFToken, Pos: l.pos}
+	}
+
+	l.skipSpaceStream()
+	l.ensureToken()
+
+	tok := l.lexToken()
+	switch tok.Type {
+	case StringToken, IntegerToken, FloatToken:
+		// 窗口内容会被后续 fill 覆盖，值必须复制出来
+		tok.Value = append([]byte(nil), tok.Value...)
+		l.endValue()
+	case NullToken, TrueToken, FalseToken:
+		l.endValue()
+	case LeftBraceToken, LeftBracketToken:
+		l.depth++
+	case RightBraceToken, RightBracketToken:
+		l.depth--
+		l.endValue()
+	}
+	return tok
+}
+
+// endValue 在一个值结束时调用，回到 valueDepth 层时标记该值已读完
+func (l *Lexer) endValue() {
+	if l.depth == l.valueDepth && l.stopAtValueEnd {
+		l.valueDone = true
+	}
+}
+
+// skipSpaceStream 跳过空白，窗口读完时继续从 r 读取
+func (l *Lexer) skipSpaceStream() {
+	for {
+		for l.pos < len(l.input) {
+			switch l.input[l.pos] {
+			case ' ', '\n', '\t', '\r':
+				l.pos++
+			default:
+				return
+			}
+		}
+		l.start = l.pos
+		if !l.fill() {
+			return
+		}
+	}
+}
+
+// ensureToken 保证从 pos 开始的整个标记都已读入窗口
+func (l *Lexer) ensureToken() {
+	l.start = l.pos
+	if l.pos >= len(l.input) {
+		return
+	}
+
+	switch c := l.input[l.pos]; {
+	case c == '"':
+		l.ensureString()
+	case c == '-' || (c >= '0' && c <= '9'):
+		l.ensureNumber()
+	case c == 'n' || c == 't' || c == 'f':
+		for len(l.input)-l.start < len("false") && l.fill() {
+		}
+	}
+}
+
+// ensureString 读到字符串的结束引号为止；读到 EOF 时交给 lexString 报告未闭合
+func (l *Lexer) ensureString() {
+	escaped := false
+	for k := 1; ; k++ {
+		for l.start+k >= len(l.input) {
+			if !l.fill() {
+				return
+			}
+		}
+		c := l.input[l.start+k]
+		switch {
+		case escaped:
+			escaped = false
+		case c == '\\':
+			escaped = true
+		case c == '"':
+			return
+		}
+	}
+}
+
+// ensureNumber 读到第一个不可能属于数字的字节为止
+func (l *Lexer) ensureNumber() {
+	for k := 1; ; k++ {
+		for l.start+k >= len(l.input) {
+			if !l.fill() {
+				return
+			}
+		}
+		switch c := l.input[l.start+k]; {
+		case c >= '0' && c <= '9', c == '.', c == 'e', c == 'E', c == '+', c == '-':
+		default:
+			return
+		}
+	}
+}
+
+// skipCompositeStream 跳过一个已读入左括号的对象或数组，pos 停在对应右括号之后。
+// keep 为 true 时保留 start（左括号）之后的全部原始字节，供调用方复制；
+// 输入在闭合前结束时返回 false
+func (l *Lexer) skipCompositeStream(keep bool) bool {
+	depth := 1
+	inString, escaped := false, false
+	for depth > 0 {
+		if l.pos >= len(l.input) {
+			if !keep {
+				l.start = l.pos
+			}
+			if !l.fill() {
+				return false
+			}
+		}
+
+		c := l.input[l.pos]
+		l.pos++
+		switch {
+		case escaped:
+			escaped = false
+		case inString:
+			if c == '\\' {
+				escaped = true
+			} else if c == '"' {
+				inString = false
+			}
+		case c == '"':
+			inString = true
+		case c == '{' || c == '[':
+			depth++
+		case c == '}' || c == ']':
+			depth--
+		}
+	}
+
+	l.depth--
+	l.endValue()
+	return true
+}
+
+// nextStreamToken 与 NextToken 相同，但输入只剩空白时直接返回 EOFToken 而不消费空白，
+// 与 encoding/json 一致，InputOffset 停在最后一个标记之后
+func (l *Lexer) nextStreamToken() Token {
+	if _, ok := l.peekByte(); !ok {
+		return Token{Type: EOFToken}
+	}
+	return l.NextToken()
+}
+
+// peekByte 返回 pos 之后第一个非空白字节，并把 pos 移到该字节（与 encoding/json 的 More 一致）；
+// 输入结束时不移动 pos
+func (l *Lexer) peekByte() (byte, bool) {
+	l.start = l.pos
+	for k := 0; ; k++ {
+		for l.pos+k >= len(l.input) {
+			if !l.fill() {
+				return 0, false
+			}
+		}
+		switch c := l.input[l.pos+k]; c {
+		case ' ', '\n', '\t', '\r':
+		default:
+			l.pos += k
+			return c, true
+		}
+	}
+}
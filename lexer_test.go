@@ -4,6 +4,7 @@ import (
 	"math"
 	"strings" // Need strings for complex JSON example
 	"testing"
+	"testing/iotest"
 )
 
 func TestLexer_NextToken(t *testing.T) {
@@ -626,3 +627,24 @@ func TestLexer_NumberPrecision(t *testing.T) {
 		})
 	}
 }
+
+// 测试流式词法分析器在每次只读一个字节时与内存输入产生相同的标记
+func TestLexer_FromReader(t *testing.T) {
+	input := `{"a\"bé😀":[1,-2.5e3,true,false,null],"long":"` +
+		strings.Repeat("x", 5000) + `\n"}  123`
+	lexer, err := NewLexerFromReader(iotest.OneByteReader(strings.NewReader(input)))
+	if err != nil {
+		t.Fatalf("NewLexerFromReader 失败: %v", err)
+	}
+	expected := NewLexer([]byte(input))
+	for {
+		want := expected.NextToken()
+		got := lexer.NextToken()
+		if got.Type != want.Type || string(got.Value) != string(want.Value) || got.FloatValue != want.FloatValue {
+			t.Fatalf("标记 = %v %q, 期望 %v %q", got.Type, got.Value, want.Type, want.Value)
+		}
+		if want.Type == EOFToken || want.Type == InvalidToken {
+			break
+		}
+	}
+}
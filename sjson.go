@@ -3,34 +3,135 @@ package sjson
 import (
 	"reflect"
 	"strings"
-	"sync"
-)
-
-// 编解码器缓存部分
-var (
-	// 结构体字段信息缓存
-	structFieldsCache sync.Map // map[reflect.Type][]structField
+	"sync/atomic"
 )
 
 // Config 用于配置JSON解析和编码的行为
 type Config struct {
 	// SortMapKeys 控制对象和map的键是否排序，默认不排序
 	SortMapKeys bool
+
+	// FloatPrecision 控制浮点数编码的有效位数。
+	// 0（默认）输出能精确往返的最短表示，与 encoding/json 一致；
+	// 大于 0 时按固定有效位数以 'g' 格式输出（旧版的快速模式相当于 6）。
+	FloatPrecision int
+
+	// NonFiniteFloats 控制 NaN / ±Inf 的编码方式，默认返回 *UnsupportedValueError
+	NonFiniteFloats NonFiniteFloatMode
+
+	// EscapeHTML 将字符串中的 <、>、& 转义为 \u003c、\u003e、\u0026，
+	// 与 encoding/json 的默认行为一致，便于把输出嵌入 HTML <script> 中
+	EscapeHTML bool
+
+	// EscapeLineTerminators 将 U+2028、U+2029 转义为 \u2028、\u2029（JSONP 场景）
+	EscapeLineTerminators bool
+
+	// ASCIIOnly 将所有非 ASCII 字符写成 \uXXXX（增补平面字符写成 UTF-16 代理对），
+	// 此时非法 UTF-8 字节至少会被替换为 \ufffd
+	ASCIIOnly bool
+
+	// InvalidUTF8 控制字符串中非法 UTF-8 字节的处理方式，默认原样输出
+	InvalidUTF8 InvalidUTF8Mode
+
+	// Canonical 按 RFC 8785（JCS）输出规范形式，结果与 Canonicalize(Marshal(v)) 相同：
+	// 结构体字段与各类 map 的键一律按 UTF-16 码元排序，数字为 ECMAScript 最短形式，
+	// 字符串只做最小转义。此时 SortMapKeys、FloatPrecision 与各转义选项不起作用；
+	// 整数按双精度处理，超过 2^53 的整数会丢失精度。规范形式要求合法的 UTF-8，
+	// 默认的 InvalidUTF8Passthrough 下遇到非法字节返回错误
+	Canonical bool
+
+	// FieldNamer 为没有通过 json 标签指定名字的字段生成 JSON 键名（如 SnakeCase），
+	// 编码与解码对称使用；nil 时使用 Go 字段名。键名在编译字段信息时计算一次，不增加每次调用的开销
+	FieldNamer *FieldNamer
+
+	// TagKey 指定读取字段名与选项的结构体标签，如 "sjson"。字段带有该标签时使用它，
+	// 否则回退到 json 标签，都没有时使用字段名。空字符串表示只读取 json 标签
+	TagKey string
+
+	// CaseSensitive 解码时只接受与字段名完全相同的键。默认与 encoding/json 一致，
+	// 精确匹配失败时按 Unicode 简单折叠做大小写不敏感匹配，此时 {"ID":1,"id":2} 两个键会写入同一字段
+	CaseSensitive bool
+
+	// DisallowUnknownFields 解码时遇到结构体中没有对应字段的键返回 *UnknownFieldError，
+	// 而不是跳过该键；对嵌入结构体提升的字段和嵌套的结构体同样生效
+	DisallowUnknownFields bool
+
+	// NumberMode 控制解码到 interface{} 时数字的表示：默认 float64，
+	// NumberAsNumber 保留原始文本为 Number，NumberAsInt64WhenIntegral 把整数解码为 int64
+	NumberMode NumberMode
+
+	// CopyStrings 控制解码出的字符串是否复制出输入。默认 StringCopy，之后复用或修改 data
+	// （如放回池中的网络读缓冲区）不会改变已解码的值；确定 data 不再变化时可设为 StringZeroCopy 省去复制
+	CopyStrings StringCopyMode
 }
 
-// 默认配置
-var defaultConfig = Config{
-	SortMapKeys: false,
+// InvalidUTF8Mode 指定编码时遇到非法 UTF-8 字节的策略
+type InvalidUTF8Mode int
+
+const (
+	// InvalidUTF8Passthrough 原样输出非法字节
+	InvalidUTF8Passthrough InvalidUTF8Mode = iota
+	// InvalidUTF8Replace 将每个非法字节替换为 \ufffd（与 encoding/json 一致）
+	InvalidUTF8Replace
+	// InvalidUTF8Reject 返回 *InvalidUTF8Error
+	InvalidUTF8Reject
+)
+
+// escapeFlags 将字符串相关的配置压缩为位标记，编码时每个流只计算一次
+func (c Config) escapeFlags() escapeFlags {
+	var f escapeFlags
+	if c.EscapeHTML {
+		f |= escapeHTML
+	}
+	if c.EscapeLineTerminators {
+		f |= escapeLineTerminators
+	}
+	if c.ASCIIOnly {
+		f |= escapeASCIIOnly
+	}
+	switch c.InvalidUTF8 {
+	case InvalidUTF8Replace:
+		f |= escapeInvalidUTF8Replace
+	case InvalidUTF8Reject:
+		f |= escapeInvalidUTF8Reject
+	}
+	return f
+}
+
+// NonFiniteFloatMode 指定 NaN 与 ±Inf 的编码策略
+type NonFiniteFloatMode int
+
+const (
+	// NonFiniteError 返回 *UnsupportedValueError（与 encoding/json 一致）
+	NonFiniteError NonFiniteFloatMode = iota
+	// NonFiniteAsNull 输出 null
+	NonFiniteAsNull
+	// NonFiniteAsString 输出带引号的 "NaN"、"+Inf"、"-Inf"
+	NonFiniteAsString
+)
+
+// 默认配置对应的 API（使用 defaultCodecs）。以原子指针保存，SetDefaultConfig 可与正在进行的编解码并发调用；
+// 每次编解码开始时取一份快照，之后的修改不影响已开始的调用
+var defaultAPI atomic.Pointer[API]
+
+// loadDefaultAPI 返回当前默认配置对应的 API
+func loadDefaultAPI() *API {
+	if api := defaultAPI.Load(); api != nil {
+		return api
+	}
+	api := apiWithConfig(Config{})
+	return &api
 }
 
 // SetDefaultConfig 设置默认的全局配置
 func SetDefaultConfig(config Config) {
-	defaultConfig = config
+	api := apiWithConfig(config)
+	defaultAPI.Store(&api)
 }
 
 // GetDefaultConfig 获取当前默认配置
 func GetDefaultConfig() Config {
-	return defaultConfig
+	return loadDefaultAPI().config
 }
 
 // rawFieldInfo 收集字段时的中间结构，记录字段深度以便解决同名冲突
@@ -39,15 +140,18 @@ type rawFieldInfo struct {
 	name      string
 	index     []int
 	omitempty bool
+	omitzero  bool // json:",omitzero"：值为零值（或 IsZero() 返回 true）时省略
 	asString  bool // 是否指定了 json:",string" 选项（数字/布尔以字符串形式编解码）
 	typ       reflect.Type
 	depth     int
 	tagged    bool // 是否显式通过 json tag 指定了名字（用于与匿名字段自身名字冲突时的优先级）
+	indirect  bool // 索引路径经过嵌入的结构体指针（*T），访问时需要逐级解引用
 }
 
-// collectRawFields 递归收集结构体字段，支持匿名（embedded）字段的提升
-// 仅支持值类型的匿名结构体字段提升（不支持匿名指针字段，以保持实现简单可靠）
-func collectRawFields(t reflect.Type, indexPrefix []int, depth int, out []rawFieldInfo) []rawFieldInfo {
+// collectRawFields 递归收集结构体字段，支持匿名（embedded）字段的提升。
+// 与 encoding/json 一致，嵌入的 T 与 *T 都会提升其字段；indirect 表示前缀路径已经过嵌入指针。
+// tagKey 非空时优先读取该标签，字段没有该标签时回退到 json 标签
+func collectRawFields(t reflect.Type, tagKey string, indexPrefix []int, depth int, indirect bool, out []rawFieldInfo) []rawFieldInfo {
 	if depth > 16 {
 		// 防止异常深度的嵌套（正常场景不会出现）
 		return out
@@ -57,18 +161,31 @@ func collectRawFields(t reflect.Type, indexPrefix []int, depth int, out []rawFie
 	for i := 0; i < numField; i++ {
 		f := t.Field(i)
 
-		// 跳过未导出字段（反射不可写；encoding/json 也会忽略）
-		if f.PkgPath != "" && !f.Anonymous {
-			continue
+		// 跳过未导出字段（反射不可写；encoding/json 也会忽略）。
+		// 未导出的嵌入字段只有（指向）结构体时才需要处理，其导出字段仍会被提升
+		if f.PkgPath != "" {
+			if !f.Anonymous {
+				continue
+			}
+			if ft := f.Type; ft.Kind() != reflect.Struct && (ft.Kind() != reflect.Ptr || ft.Elem().Kind() != reflect.Struct) {
+				continue
+			}
 		}
 
-		tag := f.Tag.Get("json")
+		tag, ok := "", false
+		if tagKey != "" {
+			tag, ok = f.Tag.Lookup(tagKey)
+		}
+		if !ok {
+			tag = f.Tag.Get("json")
+		}
 		if tag == "-" {
 			continue
 		}
 
 		name := f.Name
-		omitempty := false
+		omitempty, omitzero := false, false
+		asString := false
 		tagged := false
 
 		tagName, options, _ := strings.Cut(tag, ",")
@@ -80,8 +197,13 @@ func collectRawFields(t reflect.Type, indexPrefix []int, depth int, out []rawFie
 			for options != "" {
 				var opt string
 				opt, options, _ = strings.Cut(options, ",")
-				if opt == "omitempty" {
+				switch opt {
+				case "omitempty":
 					omitempty = true
+				case "omitzero":
+					omitzero = true
+				case "string":
+					asString = true
 				}
 			}
 		}
@@ -90,11 +212,15 @@ func collectRawFields(t reflect.Type, indexPrefix []int, depth int, out []rawFie
 		copy(curIndex, indexPrefix)
 		curIndex[len(indexPrefix)] = i
 
-		// 匿名字段：如果未显式指定 json tag 名字，且是结构体类型，则递归提升其字段
+		// 匿名字段：如果未显式指定 json tag 名字，且是结构体或结构体指针类型，则递归提升其字段
 		if f.Anonymous && !tagged {
 			ft := f.Type
 			if ft.Kind() == reflect.Struct {
-				out = collectRawFields(ft, curIndex, depth+1, out)
+				out = collectRawFields(ft, tagKey, curIndex, depth+1, indirect, out)
+				continue
+			}
+			if ft.Kind() == reflect.Ptr && ft.Name() == "" && ft.Elem().Kind() == reflect.Struct {
+				out = collectRawFields(ft.Elem(), tagKey, curIndex, depth+1, true, out)
 				continue
 			}
 			// 匿名的非结构体类型（如匿名 int、匿名接口等）按其类型名作为字段名处理，走下面通用逻辑
@@ -105,13 +231,33 @@ func collectRawFields(t reflect.Type, indexPrefix []int, depth int, out []rawFie
 			continue
 		}
 
+		// ",string" 只对标量字段（或指向标量的匿名指针类型）生效，其余类型忽略该选项（与 encoding/json 一致）
+		if asString {
+			ft := f.Type
+			if ft.Name() == "" && ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			switch ft.Kind() {
+			case reflect.Bool,
+				reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+				reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+				reflect.Float32, reflect.Float64,
+				reflect.String:
+			default:
+				asString = false
+			}
+		}
+
 		out = append(out, rawFieldInfo{
 			name:      name,
 			index:     curIndex,
 			omitempty: omitempty,
+			omitzero:  omitzero,
+			asString:  asString,
 			typ:       f.Type,
 			depth:     depth,
 			tagged:    tagged,
+			indirect:  indirect,
 		})
 	}
 
@@ -164,49 +310,64 @@ func resolveFieldConflicts(raw []rawFieldInfo) []rawFieldInfo {
 }
 
 // 获取结构体类型的字段信息（支持匿名字段提升）
-func getStructFields(t reflect.Type) []structField {
-	if cachedFields, ok := structFieldsCache.Load(t); ok {
+func (c *codecCache) getStructFields(t reflect.Type) []structField {
+	if cachedFields, ok := c.structFields.Load(t); ok {
 		return cachedFields.([]structField)
 	}
 
-	raw := collectRawFields(t, nil, 0, nil)
+	raw := collectRawFields(t, c.naming.tagKey, nil, 0, false, nil)
+	if c.naming.namer != nil {
+		// 命名策略只作用于未指定标签名的字段，且在解决同名冲突之前应用
+		for i := range raw {
+			if !raw[i].tagged {
+				raw[i].name = c.naming.namer.Name(raw[i].name)
+			}
+		}
+	}
 	resolved := resolveFieldConflicts(raw)
 
 	fields := make([]structField, 0, len(resolved))
 	for _, rf := range resolved {
 		// 预缓存字段编码器
-		fieldEncoder := getEncoder(rf.typ)
+		fieldEncoder := c.getEncoder(rf.typ)
+		if rf.asString {
+			fieldEncoder = c.newQuotedEncoder(rf.typ, fieldEncoder)
+		}
 
 		// 预计算键字节
-		keyBytes := make([]byte, 0, len(rf.name)+3)
-		keyBytes = append(keyBytes, '"')
-		keyBytes = append(keyBytes, rf.name...)
-		keyBytes = append(keyBytes, '"', ':')
+		keyBytes, escKeys := fieldKeys(rf.name)
 
 		// OPT-1: 预计算字段的 unsafe 偏移量
-		// 对于多级索引路径（匿名字段提升），需逐级累加偏移量
+		// 对于多级索引路径（匿名字段提升），需逐级累加偏移量；经过嵌入指针的字段不在同一块内存中，没有偏移量
 		offset := uintptr(0)
-		curType := t
-		for _, idx := range rf.index {
-			field := curType.Field(idx)
-			offset += field.Offset
-			curType = field.Type
+		if !rf.indirect {
+			curType := t
+			for _, idx := range rf.index {
+				field := curType.Field(idx)
+				offset += field.Offset
+				curType = field.Type
+			}
 		}
 
 		nameBytes := stringToBytes(rf.name)
 		fields = append(fields, structField{
 			name:      nameBytes,
 			keyBytes:  keyBytes,
+			escKeys:   escKeys,
 			index:     rf.index,
 			offset:    offset,
 			nameLen:   len(nameBytes),
 			nameHead:  head8(nameBytes),
 			omitempty: rf.omitempty,
+			omitzero:  rf.omitzero,
+			isZero:    isZeroFunc(rf.typ, rf.omitzero),
+			asString:  rf.asString,
+			indirect:  rf.indirect,
 			typ:       rf.typ,
 			encoder:   fieldEncoder,
 		})
 	}
 
-	structFieldsCache.Store(t, fields)
+	c.structFields.Store(t, fields)
 	return fields
 }
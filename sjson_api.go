@@ -0,0 +1,215 @@
+package sjson
+
+import (
+	"io"
+	"reflect"
+	"sync"
+	"sync/atomic"
+)
+
+// codecCache 保存按类型编译好的编解码器。包级函数共用 defaultCodecs；
+// Config.Freeze 得到的每个 API 持有独立的一份，因此可以缓存依赖配置的编解码器。
+// 字段名取决于 TagKey 与 FieldNamer，因此它们是缓存的一部分
+type codecCache struct {
+	naming fieldNaming
+
+	encoders     *sync.Map // map[reflect.Type]Encoder
+	structFields sync.Map  // map[reflect.Type][]structField
+	programs     sync.Map  // map[reflect.Type]*structOpcodeProgram
+	decPrograms  sync.Map  // map[reflect.Type]*structDecodeProgram
+
+	// 通过 RegisterTypeEncoder / RegisterTypeDecoder 注册的编解码函数；
+	// hasTypeDecoders 让解码热路径在没有注册时跳过查找
+	typeEncoders    sync.Map // map[reflect.Type]TypeEncoderFunc
+	typeDecoders    sync.Map // map[reflect.Type]TypeDecoderFunc
+	hasTypeDecoders atomic.Bool
+}
+
+// defaultCodecs 默认缓存，编码器部分即导出的 EncoderCache
+var defaultCodecs = &codecCache{encoders: &EncoderCache}
+
+func newCodecCache(naming fieldNaming) *codecCache {
+	return &codecCache{naming: naming, encoders: new(sync.Map)}
+}
+
+// fieldNaming 是配置中决定字段名的部分
+type fieldNaming struct {
+	tagKey string // 空表示只读取 json 标签
+	namer  *FieldNamer
+}
+
+func (c Config) fieldNaming() fieldNaming {
+	n := fieldNaming{tagKey: c.TagKey, namer: c.FieldNamer}
+	if n.tagKey == "json" {
+		n.tagKey = ""
+	}
+	return n
+}
+
+// namedCodecs 为包级函数与 *WithConfig 系列函数中出现过的每种字段命名方式各保存一份共享缓存，
+// 同一个结构体类型因此可以在同一进程中以不同的标签视图编解码
+var namedCodecs sync.Map // map[fieldNaming]*codecCache
+
+// sharedCodecs 返回一次性传入的配置所使用的共享缓存
+func sharedCodecs(naming fieldNaming) *codecCache {
+	if naming == (fieldNaming{}) {
+		return defaultCodecs
+	}
+	if c, ok := namedCodecs.Load(naming); ok {
+		return c.(*codecCache)
+	}
+	c, _ := namedCodecs.LoadOrStore(naming, newCodecCache(naming))
+	return c.(*codecCache)
+}
+
+// API 是 Config.Freeze 得到的编解码入口（参考 jsoniter 的 Config.Froze）。
+// 配置在 Freeze 时确定，之后不可修改；各 API 使用独立的编解码器缓存，可被多个 goroutine 并发使用
+type API struct {
+	config Config
+	escape escapeFlags
+	codecs *codecCache
+}
+
+// 预定义的 API
+var (
+	// ConfigDefault 与包级函数在默认配置下的行为相同
+	ConfigDefault = Config{}.Freeze()
+
+	// ConfigStd 贴近 encoding/json 的输出：map 键排序，转义 HTML 字符与 U+2028/U+2029，
+	// 非法 UTF-8 替换为 U+FFFD。以下情况与 encoding/json 不同：
+	//   - 非法 UTF-8 写成转义 \ufffd，基于 json/v2 实现的 encoding/json 写原字符 U+FFFD
+	//   - 底层类型为字符串且实现 encoding.TextMarshaler 的 map 键按原字符串输出（同旧版 encoding/json），
+	//     基于 json/v2 实现的 encoding/json 调用 MarshalText
+	//   - 只有指针接收者实现 encoding.TextMarshaler 的 map 键按底层类型输出或报不支持，
+	//     基于 json/v2 实现的 encoding/json 报错
+	ConfigStd = Config{
+		SortMapKeys:           true,
+		EscapeHTML:            true,
+		EscapeLineTerminators: true,
+		InvalidUTF8:           InvalidUTF8Replace,
+	}.Freeze()
+
+	// ConfigFastest 追求速度：map 键不排序，不做额外转义，浮点数按 6 位有效数字输出；
+	// 解码出的字符串直接引用输入（StringZeroCopy），调用方需保证 data 在之后不被修改
+	ConfigFastest = Config{FloatPrecision: 6, CopyStrings: StringZeroCopy}.Freeze()
+)
+
+// Freeze 固化配置，返回带有独立编解码器缓存的 API
+func (c Config) Freeze() *API {
+	return &API{config: c, escape: c.escapeFlags(), codecs: newCodecCache(c.fieldNaming())}
+}
+
+// apiWithConfig 用共享缓存包装一次性传入的配置，供 *WithConfig 系列函数使用
+func apiWithConfig(config Config) API {
+	return API{config: config, escape: config.escapeFlags(), codecs: sharedCodecs(config.fieldNaming())}
+}
+
+// Config 返回 API 固化时的配置
+func (a *API) Config() Config {
+	return a.config
+}
+
+// Marshal 将Go对象编码为JSON字节切片
+func (a *API) Marshal(v interface{}) ([]byte, error) {
+	// 估算所需缓冲区大小并获取编码器流
+	estimatedSize := estimateJSONSize(v)
+	stream := getEncoderStreamWithSize(estimatedSize, a)
+
+	// 保存编码后的结果
+	err := encodeValueToBytes(stream, reflect.ValueOf(v), reflect.TypeOf(v))
+	if err != nil {
+		releaseEncoderStream(stream)
+		return nil, err
+	}
+
+	// 复制结果（避免返回池中的缓冲区）
+	result := make([]byte, len(stream.buffer))
+	copy(result, stream.buffer)
+	releaseEncoderStream(stream)
+
+	return result, nil
+}
+
+// AppendMarshal 将 v 编码为 JSON 并追加到 dst 后面，返回追加后的切片
+func (a *API) AppendMarshal(dst []byte, v interface{}) ([]byte, error) {
+	estimatedSize := estimateJSONSize(v)
+	stream := getEncoderStreamWithSize(estimatedSize, a)
+
+	err := encodeValueToBytes(stream, reflect.ValueOf(v), reflect.TypeOf(v))
+	if err != nil {
+		releaseEncoderStream(stream)
+		return dst, err
+	}
+
+	dst = append(dst, stream.buffer...)
+	releaseEncoderStream(stream)
+	return dst, nil
+}
+
+// MarshalString 将Go对象编码为JSON字符串
+func (a *API) MarshalString(v interface{}) (string, error) {
+	estimatedSize := estimateJSONSize(v)
+	stream := getEncoderStreamWithSize(estimatedSize, a)
+
+	err := encodeValueToBytes(stream, reflect.ValueOf(v), reflect.TypeOf(v))
+	if err != nil {
+		releaseEncoderStream(stream)
+		return "", err
+	}
+
+	result := string(stream.buffer)
+	releaseEncoderStream(stream)
+
+	return result, nil
+}
+
+// MarshalIndent 与 Marshal 相同，但输出带缩进，含义同包级 MarshalIndent
+func (a *API) MarshalIndent(v interface{}, prefix, indent string) ([]byte, error) {
+	estimatedSize := estimateJSONSize(v)
+	stream := getEncoderStreamWithSize(estimatedSize*2, a)
+	stream.indenting = true
+	stream.prefix, stream.indent = prefix, indent
+
+	err := encodeValueToBytes(stream, reflect.ValueOf(v), reflect.TypeOf(v))
+	if err != nil {
+		releaseEncoderStream(stream)
+		return nil, err
+	}
+
+	result := make([]byte, len(stream.buffer))
+	copy(result, stream.buffer)
+	releaseEncoderStream(stream)
+
+	return result, nil
+}
+
+// Unmarshal 将JSON字节切片解码到Go对象
+func (a *API) Unmarshal(data []byte, v interface{}) error {
+	decoder := newDecoder(data, a)
+	defer releaseDecoder(decoder)
+	return decoder.Decode(v)
+}
+
+// UnmarshalFromReader 从io.Reader边读边解码到Go对象，值之后只允许空白
+func (a *API) UnmarshalFromReader(r io.Reader, v interface{}) error {
+	decoder := a.NewDecoder(r)
+	if err := decoder.Decode(v); err != nil {
+		if err == io.EOF {
+			return io.ErrUnexpectedEOF
+		}
+		return err
+	}
+	return decoder.expectEOF()
+}
+
+// NewEncoder 创建写入 w 的流式编码器
+func (a *API) NewEncoder(w io.Writer) *StreamEncoder {
+	return &StreamEncoder{w: w, api: a}
+}
+
+// NewDecoder 创建从 r 流式读取的解码器
+func (a *API) NewDecoder(r io.Reader) *Decoder {
+	l := newStreamLexer(r)
+	l.stopAtValueEnd = true
+	return &Decoder{lexer: l, config: a.config, codecs: a.codecs}
+}
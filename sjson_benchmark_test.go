@@ -2,6 +2,9 @@ package sjson
 
 import (
 	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/bytedance/sonic"
@@ -916,3 +919,38 @@ func BenchmarkEncoder_Parallel_Binding_Jsoniter(b *testing.B) {
 		}
 	})
 }
+
+// BenchmarkDecoder_WideStruct 衡量字段查找随结构体宽度的变化：exact 为原样的键，
+// fold 为全大写的键（走大小写不敏感匹配），unknown 为结构体中不存在的键
+func BenchmarkDecoder_WideStruct(b *testing.B) {
+	for _, n := range []int{8, 32, 128} {
+		typ := wideStructType(n)
+		inputs := map[string]func(name string, i int) string{
+			"exact":   func(name string, _ int) string { return name },
+			"fold":    func(name string, _ int) string { return strings.ToUpper(name) },
+			"unknown": func(name string, i int) string { return fmt.Sprintf("unknown_%d", i) },
+		}
+		for _, kind := range []string{"exact", "fold", "unknown"} {
+			var sb strings.Builder
+			sb.WriteByte('{')
+			for i := 0; i < n; i++ {
+				if i > 0 {
+					sb.WriteByte(',')
+				}
+				fmt.Fprintf(&sb, `"%s":%d`, inputs[kind](typ.Field(i).Tag.Get("json"), i), i)
+			}
+			sb.WriteByte('}')
+			data := []byte(sb.String())
+
+			b.Run(fmt.Sprintf("%s-%d", kind, n), func(b *testing.B) {
+				v := reflect.New(typ).Interface()
+				b.SetBytes(int64(len(data)))
+				b.ReportAllocs()
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					_ = Unmarshal(data, v)
+				}
+			})
+		}
+	}
+}
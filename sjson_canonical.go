@@ -0,0 +1,370 @@
+package sjson
+
+import (
+	"cmp"
+	"reflect"
+	"slices"
+	"strconv"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Canonicalize 将 JSON 文本改写为 RFC 8785（JSON Canonicalization Scheme）规范形式，
+// 用于签名、哈希等需要字节级稳定输出的场景：
+//   - 去除所有无意义空白；
+//   - 对象的键按 UTF-16 码元排序，重复的键返回错误；
+//   - 数字按 IEEE 754 双精度解析，再以 ECMAScript 的最短形式输出（-0 输出为 0）；
+//   - 字符串只转义 "、\ 与控制字符，非法 UTF-8 与孤立的代理项返回错误。
+//
+// 语法错误返回与 Compact 相同的 *SyntaxError。
+func Canonicalize(src []byte) ([]byte, error) {
+	return appendCanonical(nil, src)
+}
+
+func appendCanonical(dst, src []byte) ([]byte, error) {
+	c := canonicalizer{reformatter: reformatter{dst: dst, src: src}}
+	origLen := len(dst)
+	if err := c.canonicalize(); err != nil {
+		return c.dst[:origLen], err
+	}
+	return c.dst, nil
+}
+
+// encodeCanonical 实现 Config.Canonical：先按最短浮点、不做额外转义的模式紧凑编码，
+// 再把结果整体改写为规范形式。改写需要完整的值，因此期间暂停 StreamEncoder 的分块写出；
+// 缩进在改写之后重新施加。
+func encodeCanonical(stream *encoderStream, src reflect.Value, typ reflect.Type) error {
+	w, indenting := stream.w, stream.indenting
+	stream.w, stream.indenting = nil, false
+	stream.config.FloatPrecision = 0
+	stream.escape &= escapeInvalidUTF8Replace | escapeInvalidUTF8Reject
+	defer func() { stream.w, stream.indenting = w, indenting }()
+
+	start := len(stream.buffer)
+	if src.IsValid() {
+		if err := stream.codecs.getEncoder(typ).appendToBytes(stream, src); err != nil {
+			return err
+		}
+	} else {
+		stream.buffer = append(stream.buffer, nullString...)
+	}
+
+	out, err := appendCanonical(nil, stream.buffer[start:])
+	if err != nil {
+		return err
+	}
+	if indenting {
+		stream.buffer, err = appendIndent(stream.buffer[:start], out, stream.prefix, stream.indent)
+		return err
+	}
+	stream.buffer = append(stream.buffer[:start], out...)
+	return nil
+}
+
+// canonicalizer 复用 reformatter 的空白跳过、语法校验与错误信息，
+// 在此基础上解码字符串和数字并按 JCS 规则重新输出
+type canonicalizer struct {
+	reformatter
+	scratch []byte // 当前字符串解码后的内容
+}
+
+// canonicalMember 记录对象中一个键值对在 dst 中的位置，排序后按新顺序拼接
+type canonicalMember struct {
+	key        string
+	start, end int
+	offset     int // 键在 src 中的位置，用于报告重复键
+}
+
+func (c *canonicalizer) canonicalize() error {
+	if err := c.value(); err != nil {
+		return err
+	}
+	c.skipSpace()
+	if c.pos < len(c.src) {
+		return c.charError(c.src[c.pos], "after top-level value")
+	}
+	return nil
+}
+
+func (c *canonicalizer) value() error {
+	c.skipSpace()
+	if c.pos >= len(c.src) {
+		return c.eofError()
+	}
+	switch ch := c.src[c.pos]; ch {
+	case '{':
+		return c.object()
+	case '[':
+		return c.array()
+	case '"':
+		_, err := c.stringValue()
+		return err
+	case '-', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
+		return c.number()
+	case 't':
+		return c.copyLiteral("true")
+	case 'f':
+		return c.copyLiteral("false")
+	case 'n':
+		return c.copyLiteral("null")
+	default:
+		return c.charError(ch, "looking for beginning of value")
+	}
+}
+
+func (c *canonicalizer) array() error {
+	c.pos++
+	c.dst = append(c.dst, '[')
+	c.skipSpace()
+	if c.pos < len(c.src) && c.src[c.pos] == ']' {
+		c.pos++
+		c.dst = append(c.dst, ']')
+		return nil
+	}
+	for {
+		if err := c.value(); err != nil {
+			return err
+		}
+		c.skipSpace()
+		if c.pos >= len(c.src) {
+			return c.eofError()
+		}
+		switch ch := c.src[c.pos]; ch {
+		case ',':
+			c.pos++
+			c.dst = append(c.dst, ',')
+		case ']':
+			c.pos++
+			c.dst = append(c.dst, ']')
+			return nil
+		default:
+			return c.charError(ch, "after array value")
+		}
+	}
+}
+
+func (c *canonicalizer) object() error {
+	c.pos++
+	c.dst = append(c.dst, '{')
+	start := len(c.dst)
+	c.skipSpace()
+	if c.pos < len(c.src) && c.src[c.pos] == '}' {
+		c.pos++
+		c.dst = append(c.dst, '}')
+		return nil
+	}
+
+	var members []canonicalMember
+	for {
+		c.skipSpace()
+		if c.pos >= len(c.src) {
+			return c.eofError()
+		}
+		if ch := c.src[c.pos]; ch != '"' {
+			return c.charError(ch, "looking for beginning of object key string")
+		}
+		m := canonicalMember{start: len(c.dst), offset: c.pos}
+		key, err := c.stringValue()
+		if err != nil {
+			return err
+		}
+		m.key = string(key)
+
+		c.skipSpace()
+		if c.pos >= len(c.src) {
+			return c.eofError()
+		}
+		if ch := c.src[c.pos]; ch != ':' {
+			return c.charError(ch, "after object key")
+		}
+		c.pos++
+		c.dst = append(c.dst, ':')
+		if err := c.value(); err != nil {
+			return err
+		}
+		m.end = len(c.dst)
+		members = append(members, m)
+
+		c.skipSpace()
+		if c.pos >= len(c.src) {
+			return c.eofError()
+		}
+		ch := c.src[c.pos]
+		if ch == '}' {
+			c.pos++
+			break
+		}
+		if ch != ',' {
+			return c.charError(ch, "after object key:value pair")
+		}
+		c.pos++
+		c.dst = append(c.dst, ',')
+	}
+
+	if !slices.IsSortedFunc(members, compareMembers) {
+		// 稳定排序保证重复键按出现顺序相邻，报告第二次出现的位置
+		slices.SortStableFunc(members, compareMembers)
+		sorted := make([]byte, 0, len(c.dst)-start)
+		for i, m := range members {
+			if i > 0 {
+				sorted = append(sorted, ',')
+			}
+			sorted = append(sorted, c.dst[m.start:m.end]...)
+		}
+		c.dst = append(c.dst[:start], sorted...)
+	}
+	for i := 1; i < len(members); i++ {
+		if members[i].key == members[i-1].key {
+			return &SyntaxError{msg: "duplicate object key " + strconv.Quote(members[i].key), Offset: int64(members[i].offset)}
+		}
+	}
+	c.dst = append(c.dst, '}')
+	return nil
+}
+
+func compareMembers(a, b canonicalMember) int {
+	return compareUTF16(a.key, b.key)
+}
+
+// compareUTF16 按 UTF-16 码元序比较两个字符串（RFC 8785 §3.2.3）。
+// 与按 UTF-8 字节比较的结果只在增补平面字符与 U+E000~U+FFFF 之间不同
+func compareUTF16(a, b string) int {
+	for a != "" && b != "" {
+		ra, na := utf8.DecodeRuneInString(a)
+		rb, nb := utf8.DecodeRuneInString(b)
+		if ra != rb {
+			if la, lb := utf16Lead(ra), utf16Lead(rb); la != lb {
+				return cmp.Compare(la, lb)
+			}
+			// 前导代理相同，尾随代理的顺序与码点顺序一致
+			return cmp.Compare(ra, rb)
+		}
+		a, b = a[na:], b[nb:]
+	}
+	return cmp.Compare(len(a), len(b))
+}
+
+// utf16Lead 返回 r 编码为 UTF-16 后的第一个码元
+func utf16Lead(r rune) rune {
+	if r >= 0x10000 {
+		lead, _ := utf16.EncodeRune(r)
+		return lead
+	}
+	return r
+}
+
+// stringValue 校验并解码位于 pos 的字符串，按 JCS 的最小转义规则写入 dst，
+// 返回解码后的内容（在下一次调用前有效）
+func (c *canonicalizer) stringValue() ([]byte, error) {
+	start, mark := c.pos, len(c.dst)
+	if err := c.copyString(); err != nil {
+		return nil, err
+	}
+	c.dst = c.dst[:mark]
+	raw := c.src[start+1 : c.pos-1]
+
+	c.scratch = c.scratch[:0]
+	for i := 0; i < len(raw); {
+		ch := raw[i]
+		if ch != '\\' {
+			if ch < utf8.RuneSelf {
+				c.scratch = append(c.scratch, ch)
+				i++
+				continue
+			}
+			r, size := utf8.DecodeRune(raw[i:])
+			if r == utf8.RuneError && size == 1 {
+				return nil, &SyntaxError{msg: "invalid UTF-8 in string", Offset: int64(start + 1 + i)}
+			}
+			c.scratch = append(c.scratch, raw[i:i+size]...)
+			i += size
+			continue
+		}
+
+		// 转义序列已由 copyString 校验
+		switch esc := raw[i+1]; esc {
+		case 'b':
+			c.scratch = append(c.scratch, '\b')
+		case 'f':
+			c.scratch = append(c.scratch, '\f')
+		case 'n':
+			c.scratch = append(c.scratch, '\n')
+		case 'r':
+			c.scratch = append(c.scratch, '\r')
+		case 't':
+			c.scratch = append(c.scratch, '\t')
+		case 'u':
+			r := hexRune(raw[i+2 : i+6])
+			if utf16.IsSurrogate(r) {
+				var r2 rune = -1
+				if i+12 <= len(raw) && raw[i+6] == '\\' && raw[i+7] == 'u' {
+					r2 = hexRune(raw[i+8 : i+12])
+				}
+				if r = utf16.DecodeRune(r, r2); r == utf8.RuneError {
+					return nil, &SyntaxError{msg: "unpaired surrogate " + string(raw[i:i+6]) + " in string", Offset: int64(start + 1 + i)}
+				}
+				i += 6
+			}
+			c.scratch = utf8.AppendRune(c.scratch, r)
+			i += 6
+			continue
+		default: // '"'、'\\'、'/'
+			c.scratch = append(c.scratch, esc)
+		}
+		i += 2
+	}
+
+	c.dst = append(c.dst, '"')
+	for _, ch := range c.scratch {
+		if ch < utf8.RuneSelf && !safeSet[ch] {
+			c.dst = escapeStringToBytes(c.dst, ch)
+		} else {
+			c.dst = append(c.dst, ch)
+		}
+	}
+	c.dst = append(c.dst, '"')
+	return c.scratch, nil
+}
+
+// hexRune 解析已校验的 4 位十六进制数
+func hexRune(h []byte) rune {
+	var r rune
+	for _, ch := range h {
+		switch {
+		case ch >= 'a':
+			ch -= 'a' - 10
+		case ch >= 'A':
+			ch -= 'A' - 10
+		default:
+			ch -= '0'
+		}
+		r = r<<4 | rune(ch)
+	}
+	return r
+}
+
+// number 校验数字并以 ECMAScript Number.prototype.toString 的形式输出
+func (c *canonicalizer) number() error {
+	start, mark := c.pos, len(c.dst)
+	if err := c.copyNumber(); err != nil {
+		return err
+	}
+	c.dst = c.dst[:mark]
+	raw := c.src[start:c.pos]
+	f, err := strconv.ParseFloat(bytesToString(raw), 64)
+	if err != nil {
+		// 语法已校验，只可能是超出 float64 范围
+		return &SyntaxError{msg: "number " + string(raw) + " out of range", Offset: int64(start)}
+	}
+	c.dst = appendCanonicalNumber(c.dst, f)
+	return nil
+}
+
+// appendCanonicalNumber 输出 ECMAScript 形式的数字。其规则与 encoding/json 的最短表示相同
+// （[1e-6, 1e21) 之外用指数形式），只有 -0 需要写成 0
+func appendCanonicalNumber(b []byte, f float64) []byte {
+	if f == 0 {
+		return append(b, '0')
+	}
+	return appendFloatShortest(b, f, 64)
+}
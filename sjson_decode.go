@@ -2,7 +2,6 @@ package sjson
 
 import (
 	"fmt"
-	"io"
 	"reflect"
 	"sync"
 )
@@ -34,24 +33,37 @@ var decoderPool = sync.Pool{
 	},
 }
 
-// Decoder 直接从JSON文本解码到Go对象，无需中间Value对象
+// Decoder 直接从JSON文本解码到Go对象，无需中间Value对象。
+// 由 NewDecoder 创建的 Decoder 从 io.Reader 流式读取，可依次解码多个顶层值
 type Decoder struct {
 	lexer  *Lexer
 	token  Token
 	config Config
+	codecs *codecCache
+	err    error // 流式解码的粘滞错误
+
+	// 本次解码复制字符串所用的块。块中已交出的字节不会被改写，每次解码重新分配，
+	// 不在解码之间复用
+	strings []byte
+
+	// Token API 的嵌套状态：tokenState 为当前层的位置，tokenStack 保存外层状态
+	tokenState tokenState
+	tokenStack []tokenState
 }
 
 // 重置解码器状态
-func (d *Decoder) reset(input []byte, config Config) {
+func (d *Decoder) reset(input []byte, api *API) {
 	d.lexer.Reset(input)
-	d.config = config
+	d.config = api.config
+	d.codecs = api.codecs
 	d.token = Token{}
+	d.strings = nil
 }
 
 // 创建新的直接解码器
-func newDecoder(input []byte, config Config) *Decoder {
+func newDecoder(input []byte, api *API) *Decoder {
 	d := decoderPool.Get().(*Decoder)
-	d.reset(input, config)
+	d.reset(input, api)
 	d.nextToken() // 读取第一个token
 	return d
 }
@@ -59,18 +71,11 @@ func newDecoder(input []byte, config Config) *Decoder {
 // 释放解码器回对象池
 func releaseDecoder(d *Decoder) {
 	d.lexer.input = nil // 避免持有大对象的引用
+	d.codecs = nil
+	d.strings = nil
 	decoderPool.Put(d)
 }
 
-// 从io.Reader创建新的直接解码器
-func newDecoderFromReader(r io.Reader, config Config) (*Decoder, error) {
-	data, err := io.ReadAll(r)
-	if err != nil {
-		return nil, err
-	}
-	return newDecoder(data, config), nil
-}
-
 // 读取下一个token - 内联优化
 //
 //go:inline
@@ -100,8 +105,13 @@ func (d *Decoder) consumeStructDelimiter(closeChar byte) int {
 	return -1 // 错误
 }
 
-// 直接解码到目标对象
+// Decode 解码到目标对象。内存输入必须恰好是一个值；
+// NewDecoder 创建的流式解码器每次读取下一个顶层值，输入结束时返回 io.EOF
 func (d *Decoder) Decode(v interface{}) error {
+	if d.lexer.r != nil {
+		return d.decodeStream(v)
+	}
+
 	rv := reflect.ValueOf(v)
 	if rv.Kind() != reflect.Ptr || rv.IsNil() {
 		return fmt.Errorf("解码目标必须是非nil指针")
@@ -85,8 +85,9 @@ func (d *Decoder) decodeSlice(dst reflect.Value) error {
 		return d.decodeFloat64Slice(dst)
 	}
 
-	// 快速路径：[]*struct（常见场景，避免 reflect.New/MakeSlice 的反复分配）
-	if elemType.Kind() == reflect.Ptr && elemType.Elem().Kind() == reflect.Struct {
+	// 快速路径：[]*struct（常见场景，避免 reflect.New/MakeSlice 的反复分配）。
+	// 该路径直接解码对象，元素类型有自定义解码方式时走通用路径
+	if elemType.Kind() == reflect.Ptr && elemType.Elem().Kind() == reflect.Struct && !d.hasCustomDecoder(elemType) {
 		return d.decodePtrStructSlice(dst, elemType)
 	}
 
@@ -94,6 +95,12 @@ func (d *Decoder) decodeSlice(dst reflect.Value) error {
 	return d.decodeSliceGeneric(dst, elemType)
 }
 
+// hasCustomDecoder 报告指针类型 t 或其元素类型是否注册了解码函数或实现了 Unmarshaler
+func (d *Decoder) hasCustomDecoder(t reflect.Type) bool {
+	return t.Implements(jsonUnmarshalerType) || t.Implements(textUnmarshalerType) ||
+		d.codecs.typeDecoder(t) != nil || d.codecs.typeDecoder(t.Elem()) != nil
+}
+
 // decodeInterfaceSliceFast 快速解码 []interface{}
 func (d *Decoder) decodeInterfaceSliceFast(dst reflect.Value) error {
 	// 从对象池获取切片
@@ -174,7 +181,7 @@ func (d *Decoder) decodeStringSlice(dst reflect.Value) error {
 			return fmt.Errorf("期望字符串，得到: %v", d.token)
 		}
 
-		result = append(result, bytesToString(d.token.Value))
+		result = append(result, d.copyString(d.token.Value))
 		d.nextToken()
 
 		// 检查分隔符
@@ -269,7 +276,7 @@ func (d *Decoder) decodePtrStructSlice(dst reflect.Value, elemType reflect.Type)
 
 			// 直接解码对象到结构体，避免 decodeValue 的指针展开开销
 			if err := d.decodeObject(elemPtr.Elem()); err != nil {
-				return err
+				return withElemPath(err, n)
 			}
 
 		default:
@@ -313,7 +320,7 @@ func (d *Decoder) decodeSliceGeneric(dst reflect.Value, elemType reflect.Type) e
 		// 解码值
 		elem := reflect.New(elemType).Elem()
 		if err := d.decodeValue(elem); err != nil {
-			return err
+			return withElemPath(err, len(*elemValues))
 		}
 		*elemValues = append(*elemValues, elem)
 
@@ -355,7 +362,7 @@ func (d *Decoder) decodeFixedArray(dst reflect.Value) error {
 
 		// 解码到数组元素
 		if err := d.decodeValue(dst.Index(i)); err != nil {
-			return err
+			return withElemPath(err, i)
 		}
 
 		// 检查分隔符
@@ -4,6 +4,7 @@ import (
 	"encoding"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"math"
 	"reflect"
@@ -17,15 +18,23 @@ var (
 
 // 预先缓存常用的反射类型
 var (
-	interfaceType = reflect.TypeOf((*interface{})(nil)).Elem()
+	interfaceType       = reflect.TypeOf((*interface{})(nil)).Elem()
+	jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
 )
 
-// checkUnmarshaler 检查目标是否实现了 json.Unmarshaler 或 encoding.TextUnmarshaler
+// checkUnmarshaler 检查目标类型是否注册了解码函数，或实现了 json.Unmarshaler / encoding.TextUnmarshaler
 func (d *Decoder) checkUnmarshaler(dst reflect.Value) (bool, error) {
 	// 取指针地址用于接口检查
 	if !dst.CanAddr() {
 		return false, nil
 	}
+
+	// 注册的解码函数优先
+	if fn := d.codecs.typeDecoder(dst.Type()); fn != nil {
+		return true, d.callTypeDecoder(fn, dst)
+	}
+
 	ptr := dst.Addr()
 
 	// json.Unmarshaler 优先
@@ -72,10 +81,10 @@ func (d *Decoder) readRawValue() ([]byte, error) {
 	// 更好的方案：直接在字节层面跳过，不调用 nextToken
 	switch d.token.Type {
 	case NullToken, TrueToken, FalseToken, IntegerToken, FloatToken, StringToken:
-		end := d.lexer.pos
+		// 先复制再读取下一个 token：流式模式下 nextToken 可能移动窗口
+		raw := make([]byte, d.lexer.pos-start)
+		copy(raw, d.lexer.input[start:d.lexer.pos])
 		d.nextToken()
-		raw := make([]byte, end-start)
-		copy(raw, d.lexer.input[start:end])
 		return raw, nil
 	case LeftBraceToken:
 		return d.readRawObject()
@@ -88,6 +97,10 @@ func (d *Decoder) readRawValue() ([]byte, error) {
 
 // readRawObject 读取原始对象字节
 func (d *Decoder) readRawObject() ([]byte, error) {
+	if d.lexer.r != nil {
+		return d.readRawCompositeStream("对象未正确闭合")
+	}
+
 	start := d.token.Pos
 	input := d.lexer.input
 	pos := d.lexer.pos
@@ -143,6 +156,10 @@ func (d *Decoder) readRawObject() ([]byte, error) {
 
 // readRawArray 读取原始数组字节
 func (d *Decoder) readRawArray() ([]byte, error) {
+	if d.lexer.r != nil {
+		return d.readRawCompositeStream("数组未正确闭合")
+	}
+
 	start := d.token.Pos
 	input := d.lexer.input
 	pos := d.lexer.pos
@@ -196,13 +213,23 @@ func (d *Decoder) readRawArray() ([]byte, error) {
 	return raw, nil
 }
 
+// readRawCompositeStream 流式模式下读取当前对象或数组的原始字节，闭合前窗口不会丢弃 start 之后的数据
+func (d *Decoder) readRawCompositeStream(unclosed string) ([]byte, error) {
+	if !d.lexer.skipCompositeStream(true) {
+		return nil, errors.New(unclosed)
+	}
+	raw := append([]byte(nil), d.lexer.input[d.lexer.start:d.lexer.pos]...)
+	d.nextToken()
+	return raw, nil
+}
+
 // 解码任意值到目标反射值
 func (d *Decoder) decodeValue(dst reflect.Value) error {
 	if !dst.IsValid() {
 		return fmt.Errorf("解码目标无效")
 	}
 
-	// 检查 json.Unmarshaler / TextUnmarshaler（在指针解引用前）
+	// 检查注册的解码函数与 json.Unmarshaler / TextUnmarshaler（在指针解引用前）
 	// 对于可寻址的值，检查其指针是否实现了 Unmarshaler
 	if dst.CanAddr() {
 		handled, err := d.checkUnmarshaler(dst)
@@ -237,7 +264,7 @@ func (d *Decoder) decodeValue(dst reflect.Value) error {
 		dst = dst.Elem()
 	}
 
-	// 再次检查 json.Unmarshaler / TextUnmarshaler（指针解引用后，此时 dst 可寻址）
+	// 再次检查注册的解码函数与 json.Unmarshaler / TextUnmarshaler（指针解引用后，此时 dst 可寻址）
 	if dst.CanAddr() {
 		handled, err := d.checkUnmarshaler(dst)
 		if err != nil {
@@ -320,13 +347,13 @@ func (d *Decoder) decodeToInterface(dst reflect.Value) error {
 		return nil
 
 	case StringToken:
-		value := bytesToString(d.token.Value)
+		value := d.copyString(d.token.Value)
 		d.nextToken()
 		dst.Set(reflect.ValueOf(value))
 		return nil
 
 	case IntegerToken, FloatToken:
-		value := d.token.FloatValue
+		value := d.numberInterface(d.token.FloatValue, d.token.IntValue, d.token.Value, d.token.IsInteger)
 		d.nextToken()
 		dst.Set(reflect.ValueOf(value))
 		return nil
@@ -368,7 +395,7 @@ func (d *Decoder) decodeInterfaceObject(dst reflect.Value) error {
 			return fmt.Errorf("对象键必须是字符串，得到: %v", d.token)
 		}
 
-		key := bytesToString(d.token.Value)
+		key := d.copyString(d.token.Value)
 		d.nextToken()
 
 		// 键后面必须是冒号
@@ -458,12 +485,12 @@ func (d *Decoder) decodeValueDirect(v *interface{}) error {
 		return nil
 
 	case StringToken:
-		*v = bytesToString(d.token.Value)
+		*v = d.copyString(d.token.Value)
 		d.nextToken()
 		return nil
 
 	case IntegerToken, FloatToken:
-		*v = d.token.FloatValue
+		*v = d.numberInterface(d.token.FloatValue, d.token.IntValue, d.token.Value, d.token.IsInteger)
 		d.nextToken()
 		return nil
 
@@ -502,7 +529,7 @@ func (d *Decoder) decodeObjectDirect(v *interface{}) error {
 			return fmt.Errorf("对象键必须是字符串，得到: %v", d.token)
 		}
 
-		key := bytesToString(d.token.Value)
+		key := d.copyString(d.token.Value)
 		d.nextToken()
 
 		if d.token.Type != ColonToken {
@@ -607,12 +634,13 @@ func (d *Decoder) decodeNumber(value float64, intValue int64, raw []byte, isInte
 	switch dst.Kind() {
 	case reflect.Interface:
 		if dst.NumMethod() == 0 {
-			if isInteger && raw != nil {
-				// 优先用 lexer 已算好的 IntValue（零分配）
-				dst.Set(reflect.ValueOf(intValue))
-				return nil
-			}
-			dst.Set(reflect.ValueOf(value))
+			dst.Set(reflect.ValueOf(d.numberInterface(value, intValue, raw, isInteger)))
+			return nil
+		}
+	case reflect.String:
+		// Number 保留数字的原始文本
+		if isNumberType(dst.Type()) {
+			dst.SetString(d.copyString(raw))
 			return nil
 		}
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
@@ -696,12 +724,16 @@ func (d *Decoder) decodeNumber(value float64, intValue int64, raw []byte, isInte
 func (d *Decoder) decodeString(value []byte, dst reflect.Value) error {
 	kind := dst.Kind()
 	if kind == reflect.String {
-		dst.SetString(bytesToString(value))
+		// 字符串解码到 Number 时内容必须是合法的数字（与 encoding/json 一致）
+		if isNumberType(dst.Type()) && !isValidNumber(bytesToString(value)) {
+			return fmt.Errorf("json: invalid number literal, trying to unmarshal %q into Number", value)
+		}
+		dst.SetString(d.copyString(value))
 		return nil
 	}
 
 	if kind == reflect.Interface && dst.NumMethod() == 0 {
-		dst.Set(reflect.ValueOf(bytesToString(value)))
+		dst.Set(reflect.ValueOf(d.copyString(value)))
 		return nil
 	}
 
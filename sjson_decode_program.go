@@ -0,0 +1,309 @@
+package sjson
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"unsafe"
+)
+
+// 结构体解码程序。每个字段编译为一条指令，按预计算的偏移量直接写内存，
+// 省去逐字段的 fieldByIndex 与 decodeValue 分发；是否注册了解码函数、是否实现 json.Unmarshaler /
+// encoding.TextUnmarshaler 在编译时确定，热路径不再对每个值调用 ptr.Interface()。
+// 指令只处理最常见的记号（如整数字段遇到不溢出的整数），null、类型不匹配、溢出等其余情况
+// 回退到 decodeValue，保证与通用路径相同的语义和错误信息。
+type decodeOpcode byte
+
+const (
+	decCall decodeOpcode = iota // 通过 decodeValue 解码
+	decBool
+	decInt
+	decUint
+	decFloat32
+	decFloat64
+	decString
+	decStruct // 嵌套结构体：在字段地址上执行其解码程序
+	decPtr    // 指针：null 置为 nil，否则（必要时分配后）在指向的地址上执行 elem
+	decSlice  // []T：元素按 stride 写入新分配的底层数组
+)
+
+// decodeInstr 是一个值的解码指令。指针与切片的元素指令由 elem 指向
+type decodeInstr struct {
+	op     decodeOpcode
+	kind   reflect.Kind // 整数的具体宽度
+	typ    reflect.Type
+	elem   *decodeInstr
+	stride uintptr // 切片元素大小
+}
+
+// structDecodeProgram 与 fields 一一对应；嵌套结构体的程序在执行时按类型查找，以支持递归类型
+type structDecodeProgram struct {
+	typ    reflect.Type
+	fields []structField
+	ops    []decodeInstr
+	lookup fieldLookup
+}
+
+// getDecodeProgram 返回结构体类型 t 的解码程序
+func (c *codecCache) getDecodeProgram(t reflect.Type) *structDecodeProgram {
+	if cached, ok := c.decPrograms.Load(t); ok {
+		return cached.(*structDecodeProgram)
+	}
+
+	fields := c.getStructFields(t)
+	program := &structDecodeProgram{
+		typ:    t,
+		fields: fields,
+		ops:    make([]decodeInstr, len(fields)),
+		lookup: newFieldLookup(fields),
+	}
+	for i := range fields {
+		field := &fields[i]
+		// ",string" 与经由嵌入指针提升的字段没有固定的写入方式，走结构体字段的通用路径
+		if field.asString || field.indirect {
+			program.ops[i] = decodeInstr{op: decCall, kind: field.typ.Kind(), typ: field.typ}
+			continue
+		}
+		program.ops[i] = *c.compileDecodeInstr(field.typ, nil)
+	}
+	actual, _ := c.decPrograms.LoadOrStore(t, program)
+	return actual.(*structDecodeProgram)
+}
+
+// compileDecodeInstr 为类型 t 的值编译解码指令。compiling 记录正在编译 elem 的指针、切片类型，
+// 递归类型（如 type Tree []Tree）再次遇到时复用同一条指令（与 compileOpcode 相同）
+func (c *codecCache) compileDecodeInstr(t reflect.Type, compiling map[reflect.Type]*decodeInstr) *decodeInstr {
+	if in := compiling[t]; in != nil {
+		return in
+	}
+	in := &decodeInstr{op: decCall, kind: t.Kind(), typ: t}
+	if c.hasUnmarshaler(t) || isNumberType(t) {
+		return in
+	}
+	if k := t.Kind(); k == reflect.Ptr || k == reflect.Slice {
+		if compiling == nil {
+			compiling = make(map[reflect.Type]*decodeInstr)
+		}
+		compiling[t] = in
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		in.op = decBool
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		in.op = decInt
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		in.op = decUint
+	case reflect.Float32:
+		in.op = decFloat32
+	case reflect.Float64:
+		in.op = decFloat64
+	case reflect.String:
+		in.op = decString
+	case reflect.Struct:
+		in.op = decStruct
+	case reflect.Ptr:
+		in.op, in.elem = decPtr, c.compileDecodeInstr(t.Elem(), compiling)
+	case reflect.Slice:
+		if hasSliceFastPath(t.Elem()) {
+			break
+		}
+		in.op, in.elem, in.stride = decSlice, c.compileDecodeInstr(t.Elem(), compiling), t.Elem().Size()
+	}
+	return in
+}
+
+// hasUnmarshaler 报告类型 t 的值是否由注册的解码函数、json.Unmarshaler 或 encoding.TextUnmarshaler 解码
+// （与 checkUnmarshaler 的判断一致：方法在 *t 上查找）
+func (c *codecCache) hasUnmarshaler(t reflect.Type) bool {
+	if c.typeDecoder(t) != nil {
+		return true
+	}
+	pt := reflect.PointerTo(t)
+	return pt.Implements(jsonUnmarshalerType) || pt.Implements(textUnmarshalerType)
+}
+
+// hasSliceFastPath 报告 decodeSlice 是否为该元素类型准备了专门的路径（[]byte 按 base64 解码），
+// 这些切片仍交给 decodeSlice，保持原有语义
+func hasSliceFastPath(elem reflect.Type) bool {
+	switch {
+	case elem.Kind() == reflect.Uint8:
+		return true
+	case elem.Kind() == reflect.Interface && elem.NumMethod() == 0:
+		return true
+	case elem == exactIntType || elem == exactStringType || elem == exactFloat64Type:
+		return true
+	case elem.Kind() == reflect.Ptr && elem.Elem().Kind() == reflect.Struct:
+		return true
+	}
+	return false
+}
+
+// decodeInstr 把当前值解码到 ptr 指向的内存
+func (d *Decoder) decodeInstr(in *decodeInstr, ptr unsafe.Pointer) error {
+	switch in.op {
+	case decBool:
+		switch d.token.Type {
+		case TrueToken:
+			*(*bool)(ptr) = true
+			d.nextToken()
+			return nil
+		case FalseToken:
+			*(*bool)(ptr) = false
+			d.nextToken()
+			return nil
+		}
+	case decInt:
+		if d.token.Type == IntegerToken && d.token.IsInteger && storeInt(ptr, in.kind, d.token.IntValue) {
+			d.nextToken()
+			return nil
+		}
+	case decUint:
+		if d.token.Type == IntegerToken && d.token.IsInteger && d.token.IntValue >= 0 &&
+			storeUint(ptr, in.kind, uint64(d.token.IntValue)) {
+			d.nextToken()
+			return nil
+		}
+	case decFloat32:
+		if d.token.Type == IntegerToken || d.token.Type == FloatToken {
+			f := float32(d.token.FloatValue)
+			if !math.IsInf(float64(f), 0) {
+				*(*float32)(ptr) = f
+				d.nextToken()
+				return nil
+			}
+		}
+	case decFloat64:
+		if d.token.Type == IntegerToken || d.token.Type == FloatToken {
+			*(*float64)(ptr) = d.token.FloatValue
+			d.nextToken()
+			return nil
+		}
+	case decString:
+		if d.token.Type == StringToken {
+			*(*string)(ptr) = d.copyString(d.token.Value)
+			d.nextToken()
+			return nil
+		}
+	case decStruct:
+		if d.token.Type == LeftBraceToken {
+			d.nextToken()
+			// 空对象不改变结构体（与 decodeObject 一致）
+			if d.token.Type == RightBraceToken {
+				d.nextToken()
+				return nil
+			}
+			return d.decodeStructFields(d.codecs.getDecodeProgram(in.typ), ptr, reflect.Value{})
+		}
+	case decPtr:
+		if d.token.Type == NullToken {
+			d.nextToken()
+			*(*unsafe.Pointer)(ptr) = nil
+			return nil
+		}
+		p := *(*unsafe.Pointer)(ptr)
+		if p == nil {
+			p = reflect.New(in.typ.Elem()).UnsafePointer()
+			*(*unsafe.Pointer)(ptr) = p
+		}
+		return d.decodeInstr(in.elem, p)
+	case decSlice:
+		if d.token.Type == LeftBracketToken {
+			return d.decodeSliceElems(in, ptr)
+		}
+	}
+	return d.decodeValue(reflect.NewAt(in.typ, ptr).Elem())
+}
+
+// decodeSliceElems 解码数组到 ptr 处的切片。与 decodeSliceGeneric 一样总是创建新切片，
+// 出错时不修改原切片
+func (d *Decoder) decodeSliceElems(in *decodeInstr, ptr unsafe.Pointer) error {
+	d.nextToken()
+	if d.token.Type == RightBracketToken {
+		d.nextToken()
+		*(*sliceHeader)(ptr) = sliceHeader{data: reflect.MakeSlice(in.typ, 0, 0).UnsafePointer()}
+		return nil
+	}
+
+	buf := reflect.MakeSlice(in.typ, 8, 8)
+	data := buf.UnsafePointer()
+	n := 0
+	for {
+		if n == buf.Len() {
+			grown := reflect.MakeSlice(in.typ, 2*n, 2*n)
+			reflect.Copy(grown, buf)
+			buf, data = grown, grown.UnsafePointer()
+		}
+		if err := d.decodeInstr(in.elem, unsafe.Add(data, uintptr(n)*in.stride)); err != nil {
+			return withElemPath(err, n)
+		}
+		n++
+
+		switch d.consumeStructDelimiter(']') {
+		case 0:
+		case 1:
+			*(*sliceHeader)(ptr) = sliceHeader{data: data, len: n, cap: buf.Len()}
+			return nil
+		default:
+			return fmt.Errorf("数组中意外的标记: %v", d.token)
+		}
+	}
+}
+
+// storeInt 按宽度写入有符号整数，溢出时不写入并返回 false
+func storeInt(ptr unsafe.Pointer, kind reflect.Kind, n int64) bool {
+	switch kind {
+	case reflect.Int8:
+		if n < math.MinInt8 || n > math.MaxInt8 {
+			return false
+		}
+		*(*int8)(ptr) = int8(n)
+	case reflect.Int16:
+		if n < math.MinInt16 || n > math.MaxInt16 {
+			return false
+		}
+		*(*int16)(ptr) = int16(n)
+	case reflect.Int32:
+		if n < math.MinInt32 || n > math.MaxInt32 {
+			return false
+		}
+		*(*int32)(ptr) = int32(n)
+	case reflect.Int64:
+		*(*int64)(ptr) = n
+	default:
+		if int64(int(n)) != n {
+			return false
+		}
+		*(*int)(ptr) = int(n)
+	}
+	return true
+}
+
+// storeUint 按宽度写入无符号整数，溢出时不写入并返回 false
+func storeUint(ptr unsafe.Pointer, kind reflect.Kind, n uint64) bool {
+	switch kind {
+	case reflect.Uint8:
+		if n > math.MaxUint8 {
+			return false
+		}
+		*(*uint8)(ptr) = uint8(n)
+	case reflect.Uint16:
+		if n > math.MaxUint16 {
+			return false
+		}
+		*(*uint16)(ptr) = uint16(n)
+	case reflect.Uint32:
+		if n > math.MaxUint32 {
+			return false
+		}
+		*(*uint32)(ptr) = uint32(n)
+	case reflect.Uint64:
+		*(*uint64)(ptr) = n
+	default:
+		if uint64(uint(n)) != n {
+			return false
+		}
+		*(*uint)(ptr) = uint(n)
+	}
+	return true
+}
@@ -1,6 +1,7 @@
 package sjson
 
 import (
+	"errors"
 	"fmt"
 )
 
@@ -29,6 +30,10 @@ func (d *Decoder) skipValue() error {
 // skipObjectFast 字节级快速跳过对象
 // 直接在原始字节上扫描，不做完整的Token解析
 func (d *Decoder) skipObjectFast() error {
+	if d.lexer.r != nil {
+		return d.skipCompositeStream("对象未正确闭合")
+	}
+
 	input := d.lexer.input
 	pos := d.lexer.pos
 	inputLen := d.lexer.inputLen
@@ -98,6 +103,10 @@ func (d *Decoder) skipObjectFast() error {
 
 // skipArrayFast 字节级快速跳过数组
 func (d *Decoder) skipArrayFast() error {
+	if d.lexer.r != nil {
+		return d.skipCompositeStream("数组未正确闭合")
+	}
+
 	input := d.lexer.input
 	pos := d.lexer.pos
 	inputLen := d.lexer.inputLen
@@ -161,6 +170,15 @@ func (d *Decoder) skipArrayFast() error {
 	return nil
 }
 
+// skipCompositeStream 流式模式下跳过当前对象或数组，边扫描边丢弃已跳过的数据
+func (d *Decoder) skipCompositeStream(unclosed string) error {
+	if !d.lexer.skipCompositeStream(false) {
+		return errors.New(unclosed)
+	}
+	d.nextToken()
+	return nil
+}
+
 // 以下是旧的实现，保留作为备用
 
 // skipObject 跳过对象 - 使用Token解析版本
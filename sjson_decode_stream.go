@@ -0,0 +1,102 @@
+package sjson
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"reflect"
+)
+
+// NewDecoder 创建从 r 流式读取的解码器，对应 encoding/json.NewDecoder。
+// 输入按需读入滑动窗口，内存占用取决于单个标记的大小而不是整个输入；
+// Decode 在一个顶层值结束后立即返回，不会为了预读下一个值而阻塞。
+func NewDecoder(r io.Reader) *Decoder {
+	return loadDefaultAPI().NewDecoder(r)
+}
+
+// decodeStream 从流中解码下一个顶层值。出错后解码器不再可用，之后的调用都返回同一个错误
+func (d *Decoder) decodeStream(v interface{}) error {
+	if d.err != nil {
+		return d.err
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("解码目标必须是非nil指针")
+	}
+
+	if err := d.tokenPrepareForDecode(); err != nil {
+		return err
+	}
+	if !d.tokenValueAllowed() {
+		d.err = &SyntaxError{msg: "not at beginning of value", Offset: d.InputOffset()}
+		return d.err
+	}
+
+	// 只解码当前层的一个值：回到这一层时词法分析器停止读取
+	d.lexer.valueDepth = d.lexer.depth
+	d.lexer.valueDone = false
+	d.token = d.lexer.nextStreamToken()
+	if d.token.Type == EOFToken {
+		d.err = d.lexer.readErr()
+		if d.err == io.EOF && d.lexer.depth > 0 {
+			d.err = io.ErrUnexpectedEOF
+		}
+		return d.err
+	}
+
+	if err := d.decodeValue(rv); err != nil {
+		if rerr := d.lexer.rerr; rerr != nil && rerr != io.EOF {
+			err = rerr
+		} else if d.token.Type == EOFToken && !d.lexer.valueDone {
+			err = io.ErrUnexpectedEOF
+		} else if d.token.Type == InvalidToken && d.invalidTokenError(d.token, "") == io.ErrUnexpectedEOF {
+			// 值在记号中间被截断（如 ["abc、[tru、[1.）
+			err = io.ErrUnexpectedEOF
+		}
+		d.err = err
+		return err
+	}
+	d.tokenValueEnd()
+	return nil
+}
+
+// expectEOF 确认最后一个值之后只剩空白，供 UnmarshalFromReader 使用
+func (d *Decoder) expectEOF() error {
+	d.lexer.valueDone = false
+	d.nextToken()
+	if d.token.Type != EOFToken {
+		return fmt.Errorf("JSON解析完成后存在多余内容: %v", d.token)
+	}
+	if err := d.lexer.readErr(); err != io.EOF {
+		return err
+	}
+	return nil
+}
+
+// More 报告输入中是否还有下一个值：当前数组或对象中还有元素，或顶层还有下一个值
+func (d *Decoder) More() bool {
+	c, ok := d.lexer.peekByte()
+	return ok && c != ']' && c != '}'
+}
+
+// Buffered 返回已读入但尚未解码的数据，在 Decode 之后调用前有效
+func (d *Decoder) Buffered() io.Reader {
+	return bytes.NewReader(d.lexer.input[d.lexer.pos:])
+}
+
+// DisallowUnknownFields 使之后的 Decode 在遇到结构体中没有对应字段的键时返回 *UnknownFieldError，
+// 与 Config.DisallowUnknownFields 相同
+func (d *Decoder) DisallowUnknownFields() {
+	d.config.DisallowUnknownFields = true
+}
+
+// UseNumber 使之后的 Decode 把解码到 interface{} 的数字表示为 Number，
+// 与 Config.NumberMode 设为 NumberAsNumber 相同
+func (d *Decoder) UseNumber() {
+	d.config.NumberMode = NumberAsNumber
+}
+
+// InputOffset 返回当前解码位置在整个输入中的字节偏移，即最近解码的值之后的位置
+func (d *Decoder) InputOffset() int64 {
+	return d.lexer.offset + int64(d.lexer.pos)
+}
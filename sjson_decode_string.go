@@ -0,0 +1,55 @@
+package sjson
+
+import "unsafe"
+
+// StringCopyMode 指定解码出的字符串（包括 map 键与 Number）是否与输入共享内存
+type StringCopyMode int
+
+const (
+	// StringCopy 把字符串复制出输入（默认）。调用方之后修改或复用 data 不影响已解码的值；
+	// 同一次解码中的短字符串分配在共享的块上，分配次数与字符串个数无关
+	StringCopy StringCopyMode = iota
+	// StringZeroCopy 不复制：不含转义的字符串直接引用 data 中的字节。
+	// 只有在 data 此后不再被修改、复用时才安全，否则已解码的值会随之改变
+	StringZeroCopy
+)
+
+const (
+	// stringArenaChunk 是字符串块的最大容量
+	stringArenaChunk = 4 << 10
+	// stringArenaMaxLen 以上的字符串单独分配，避免一个长字符串独占或浪费一整块
+	stringArenaMaxLen = 512
+)
+
+// copyString 返回 b 对应的字符串。b 引用输入时按 CopyStrings 复制到本次解码的字符串块上；
+// 带转义的字符串和流式解码的值已由词法分析器单独分配，直接使用
+func (d *Decoder) copyString(b []byte) string {
+	if d.config.CopyStrings == StringZeroCopy || !d.lexer.aliasesInput(b) {
+		return bytesToString(b)
+	}
+	n := len(b)
+	if n > stringArenaMaxLen {
+		return string(b)
+	}
+	if cap(d.strings)-len(d.strings) < n {
+		// 剩余输入的长度是之后所有未转义字符串总长的上界，小输入只需分配一次刚好够用的块
+		size := d.lexer.inputLen - d.lexer.pos + n
+		if size > stringArenaChunk {
+			size = stringArenaChunk
+		}
+		d.strings = make([]byte, 0, size)
+	}
+	start := len(d.strings)
+	d.strings = append(d.strings, b...)
+	return bytesToString(d.strings[start:])
+}
+
+// aliasesInput 报告 b 是否指向输入缓冲区
+func (l *Lexer) aliasesInput(b []byte) bool {
+	if len(b) == 0 || len(l.input) == 0 {
+		return false
+	}
+	p := uintptr(unsafe.Pointer(unsafe.SliceData(b)))
+	base := uintptr(unsafe.Pointer(unsafe.SliceData(l.input)))
+	return p >= base && p < base+uintptr(len(l.input))
+}
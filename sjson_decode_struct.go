@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"reflect"
 	"strconv"
+	"unsafe"
 )
 
 // 解码对象
@@ -80,7 +81,7 @@ func (d *Decoder) decodeMapStringInterface(m map[string]interface{}) error {
 			return fmt.Errorf("对象键必须是字符串，得到: %v", d.token)
 		}
 
-		key := bytesToString(d.token.Value)
+		key := d.copyString(d.token.Value)
 		d.nextToken()
 
 		// 键后面必须是冒号
@@ -138,7 +139,7 @@ func (d *Decoder) decodeMap(dst reflect.Value) error {
 			return fmt.Errorf("对象键必须是字符串，得到: %v", d.token)
 		}
 
-		keyStr := bytesToString(d.token.Value)
+		keyStr := d.copyString(d.token.Value)
 		d.nextToken()
 
 		// 键后面必须是冒号
@@ -150,7 +151,7 @@ func (d *Decoder) decodeMap(dst reflect.Value) error {
 		// 解码值
 		valueElem := reflect.New(elemType).Elem()
 		if err := d.decodeValue(valueElem); err != nil {
-			return err
+			return withKeyPath(err, keyStr)
 		}
 
 		// 将字符串键转换为 map 的键类型
@@ -184,7 +185,7 @@ func (d *Decoder) decodeMapStringString(dst reflect.Value) error {
 			return fmt.Errorf("对象键必须是字符串，得到: %v", d.token)
 		}
 
-		key := bytesToString(d.token.Value)
+		key := d.copyString(d.token.Value)
 		d.nextToken()
 
 		if d.token.Type != ColonToken {
@@ -196,7 +197,7 @@ func (d *Decoder) decodeMapStringString(dst reflect.Value) error {
 			return fmt.Errorf("期望字符串值，得到: %v", d.token)
 		}
 
-		m[key] = bytesToString(d.token.Value)
+		m[key] = d.copyString(d.token.Value)
 		d.nextToken()
 
 		// 检查分隔符
@@ -237,12 +238,82 @@ func equalFoldASCII(a, b []byte) bool {
 	return true
 }
 
+// exactFieldIndex 线性扫描精确匹配的字段，仅在字段查找表未能建立时使用。
+// (len, head8) 二元组等值比较（jsoniter/sonic 同款）：
+// 大多数字段名 <= 8 字节，一次 uint64 比较即可判定，零 memcmp、零函数调用
+func exactFieldIndex(fields []structField, keyBytes []byte) int {
+	keyLen := len(keyBytes)
+	keyHead := head8(keyBytes)
+	if keyLen <= 8 {
+		for i := range fields {
+			if fields[i].nameLen == keyLen && fields[i].nameHead == keyHead {
+				return i
+			}
+		}
+		return -1
+	}
+	// >8 字节：(len, head) 匹配后仍需 bytes.Equal 排除前缀碰撞
+	for i := range fields {
+		if fields[i].nameLen == keyLen && fields[i].nameHead == keyHead &&
+			bytes.Equal(fields[i].name, keyBytes) {
+			return i
+		}
+	}
+	return -1
+}
+
+// UnknownFieldError 表示在 DisallowUnknownFields 模式下遇到了结构体中没有对应字段的键。
+// Path 为该键所在对象在文档中的位置，例如 "items[2].owner"；顶层对象为空
+type UnknownFieldError struct {
+	Key    string
+	Path   string
+	Offset int64 // 键（开头的引号）在输入中的字节偏移
+}
+
+func (e *UnknownFieldError) Error() string {
+	if e.Path == "" {
+		return "json: unknown field " + strconv.Quote(e.Key)
+	}
+	return "json: unknown field " + strconv.Quote(e.Key) + " in " + strconv.Quote(e.Path)
+}
+
+// withKeyPath 在错误向上传播时为 UnknownFieldError 补全对象键路径，只在出错路径上执行
+func withKeyPath(err error, key string) error {
+	if e, ok := err.(*UnknownFieldError); ok {
+		if e.Path == "" || e.Path[0] == '[' {
+			e.Path = key + e.Path
+		} else {
+			e.Path = key + "." + e.Path
+		}
+	}
+	return err
+}
+
+// withElemPath 为数组元素补全下标路径
+func withElemPath(err error, i int) error {
+	if e, ok := err.(*UnknownFieldError); ok {
+		if e.Path == "" || e.Path[0] == '[' {
+			e.Path = "[" + strconv.Itoa(i) + "]" + e.Path
+		} else {
+			e.Path = "[" + strconv.Itoa(i) + "]." + e.Path
+		}
+	}
+	return err
+}
+
 // 解码结构体
 func (d *Decoder) decodeStruct(dst reflect.Value) error {
-	structType := dst.Type()
+	program := d.codecs.getDecodeProgram(dst.Type())
+	if !dst.CanAddr() {
+		return d.decodeStructFields(program, nil, dst)
+	}
+	return d.decodeStructFields(program, unsafe.Pointer(dst.UnsafeAddr()), dst)
+}
 
-	// 预先获取所有字段信息，避免重复查找
-	fields := getStructFields(structType)
+// decodeStructFields 解码对象的键值对（左大括号已读取）。base 为结构体的地址，为 nil 时所有字段走通用路径；
+// dst 为同一结构体的 reflect.Value，嵌套执行时为无效值，遇到需要通用路径的字段时才按地址构造
+func (d *Decoder) decodeStructFields(program *structDecodeProgram, base unsafe.Pointer, dst reflect.Value) error {
+	fields := program.fields
 
 	for {
 		// 键必须是字符串
@@ -251,6 +322,7 @@ func (d *Decoder) decodeStruct(dst reflect.Value) error {
 		}
 
 		keyBytes := d.token.Value
+		keyOffset := d.lexer.offset + int64(d.token.Pos)
 		d.nextToken()
 
 		// 键后面必须是冒号
@@ -259,49 +331,44 @@ func (d *Decoder) decodeStruct(dst reflect.Value) error {
 		}
 		d.nextToken()
 
-		// (len, head8) 二元组等值比较（jsoniter/sonic 同款）：
-		// 大多数字段名 <= 8 字节，一次 uint64 比较即可判定，零 memcmp、零函数调用；
-		// 取代原先的线性 bytes.Equal 扫描与排序二分查找（含额外的 sync.Map.Load）。
-		keyLen := len(keyBytes)
-		keyHead := head8(keyBytes)
-		fieldPos := -1
-		if keyLen <= 8 {
-			for i := range fields {
-				if fields[i].nameLen == keyLen && fields[i].nameHead == keyHead {
-					fieldPos = i
-					break
-				}
-			}
-		} else {
-			// >8 字节：(len, head) 匹配后仍需 bytes.Equal 排除前缀碰撞
-			for i := range fields {
-				if fields[i].nameLen == keyLen && fields[i].nameHead == keyHead &&
-					bytes.Equal(fields[i].name, keyBytes) {
-					fieldPos = i
-					break
-				}
-			}
-		}
-
-		// 精确匹配失败时，尝试大小写不敏感兜底匹配（与 encoding/json 行为一致）。
-		// 零分配优化：逐字段做 ASCII 大小写折叠比较，避免 strings.ToLower 的内存分配。
-		if fieldPos < 0 {
-			for i := range fields {
-				if equalFoldASCII(keyBytes, fields[i].name) {
-					fieldPos = i
-					break
-				}
-			}
+		// 按类型预建的完美哈希表查找字段，代价与字段数无关。
+		// 精确匹配失败时大小写不敏感匹配（与 encoding/json 行为一致），CaseSensitive 时关闭
+		fieldPos := program.lookup.find(fields, keyBytes)
+		if fieldPos < 0 && !d.config.CaseSensitive {
+			fieldPos = program.lookup.findFold(keyBytes)
 		}
 
 		if fieldPos >= 0 {
 			// 字段存在，解码值
 			field := &fields[fieldPos]
-			fv := fieldByIndex(dst, field.index)
-			if err := d.decodeValue(fv); err != nil {
+			in := &program.ops[fieldPos]
+			var err error
+			if in.op != decCall && base != nil {
+				err = d.decodeInstr(in, unsafe.Add(base, field.offset))
+			} else {
+				if !dst.IsValid() {
+					dst = reflect.NewAt(program.typ, base).Elem()
+				}
+				fv, ferr := structFieldForSet(dst, field)
+				if ferr != nil {
+					return ferr
+				}
+				if field.asString {
+					err = d.decodeQuoted(fv)
+				} else {
+					err = d.decodeValue(fv)
+				}
+			}
+			if err != nil {
+				if _, ok := err.(*UnknownFieldError); ok {
+					return withKeyPath(err, string(keyBytes))
+				}
 				return fmt.Errorf("解码字段 %s 出错: %w", bytesToString(keyBytes), err)
 			}
 		} else {
+			if d.config.DisallowUnknownFields {
+				return &UnknownFieldError{Key: string(keyBytes), Offset: keyOffset}
+			}
 			// 字段不存在，跳过值
 			if err := d.skipValue(); err != nil {
 				return err
@@ -322,6 +389,67 @@ done4:
 	return nil
 }
 
+// structFieldForSet 取解码要写入的字段。经由嵌入指针提升的字段在路径上遇到 nil 指针时分配新值；
+// 嵌入的是未导出结构体的指针时无法分配，返回与 encoding/json 相同的错误
+func structFieldForSet(v reflect.Value, field *structField) (reflect.Value, error) {
+	if !field.indirect {
+		return fieldByIndex(v, field.index), nil
+	}
+	for i, x := range field.index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				if !v.CanSet() {
+					return reflect.Value{}, fmt.Errorf("json: cannot set embedded pointer to unexported struct: %v", v.Type().Elem())
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, nil
+}
+
+// decodeQuoted 解码带 `json:",string"` 选项的字段：值必须是一个字符串，
+// 其内容再按 JSON 字面量（数字、布尔、null 或带引号的字符串）解码到字段，错误信息与 encoding/json 一致
+func (d *Decoder) decodeQuoted(dst reflect.Value) error {
+	switch d.token.Type {
+	case NullToken:
+		return d.decodeValue(dst)
+	case StringToken:
+	default:
+		if err := d.skipValue(); err != nil {
+			return err
+		}
+		return fmt.Errorf("json: invalid use of ,string struct tag, trying to unmarshal unquoted value into %v", dst.Type())
+	}
+
+	item := d.token.Value
+	d.nextToken()
+
+	invalid := func() error {
+		return fmt.Errorf("json: invalid use of ,string struct tag, trying to unmarshal %q into %v", item, dst.Type())
+	}
+
+	// 内层必须恰好是一个标量字面量，不允许对象、数组或多余内容；Number 的内层只能是数字
+	check := Lexer{input: item, inputLen: len(item)}
+	switch check.NextToken().Type {
+	case StringToken:
+		if isNumberType(dst.Type()) {
+			return invalid()
+		}
+	case IntegerToken, FloatToken, TrueToken, FalseToken, NullToken:
+	default:
+		return invalid()
+	}
+	if check.NextToken().Type != EOFToken {
+		return invalid()
+	}
+
+	inner := Decoder{lexer: NewLexer(item), config: d.config, codecs: d.codecs}
+	inner.nextToken()
+	return inner.decodeValue(dst)
+}
 
 // convertMapKey 将字符串键转换为 map 的键类型
 func convertMapKey(s string, keyType reflect.Type) (reflect.Value, error) {
@@ -1,8 +1,16 @@
 package sjson
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
 	"testing"
+	"testing/iotest"
+	"time"
 )
 
 // 用于测试直接解码器的结构体
@@ -176,6 +184,792 @@ func TestUnmarshalErrors(t *testing.T) {
 	}
 }
 
+// 带 ",string" 选项的结构体
+type quotedFieldsStruct struct {
+	ID     int64    `json:"id,string"`
+	Count  uint8    `json:"count,string"`
+	Ratio  float64  `json:"ratio,string"`
+	OK     bool     `json:"ok,string"`
+	Name   string   `json:"name,string"`
+	Ptr    *int     `json:"ptr,string"`
+	NilPtr *int     `json:"nil_ptr,string"`
+	Tags   []string `json:"tags,string"` // 非标量类型忽略 ,string
+}
+
+// 测试 ",string" 选项的编解码与 encoding/json 一致
+func TestQuotedStringOption(t *testing.T) {
+	n := 7
+	input := quotedFieldsStruct{
+		ID: 1234567890123, Count: 5, Ratio: 0.25, OK: true,
+		Name: `say "hi"`, Ptr: &n, Tags: []string{"a"},
+	}
+
+	got, err := Marshal(input)
+	if err != nil {
+		t.Fatalf("Marshal 失败: %v", err)
+	}
+	expected, _ := json.Marshal(input)
+	if string(got) != string(expected) {
+		t.Errorf("Marshal = %s, 期望 %s", got, expected)
+	}
+
+	var decoded quotedFieldsStruct
+	if err := Unmarshal(expected, &decoded); err != nil {
+		t.Fatalf("Unmarshal 失败: %v", err)
+	}
+	again, _ := json.Marshal(decoded)
+	if string(again) != string(expected) {
+		t.Errorf("Unmarshal 结果 = %s, 期望 %s", again, expected)
+	}
+
+	// 引号内的 null 和字段本身为 null
+	var nulls quotedFieldsStruct
+	if err := Unmarshal([]byte(`{"ptr":"null","id":null}`), &nulls); err != nil || nulls.Ptr != nil || nulls.ID != 0 {
+		t.Errorf("null 处理异常: %+v, %v", nulls, err)
+	}
+}
+
+// 测试 ",string" 选项的错误信息
+func TestQuotedStringOptionErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		msg   string
+	}{
+		{`{"id":123}`, `json: invalid use of ,string struct tag, trying to unmarshal unquoted value into int64`},
+		{`{"id":"abc"}`, `json: invalid use of ,string struct tag, trying to unmarshal "abc" into int64`},
+		{`{"id":""}`, `json: invalid use of ,string struct tag, trying to unmarshal "" into int64`},
+		{`{"ok":"yes"}`, `json: invalid use of ,string struct tag, trying to unmarshal "yes" into bool`},
+		{`{"name":"abc"}`, `json: invalid use of ,string struct tag, trying to unmarshal "abc" into string`},
+		{`{"id":"[1]"}`, `json: invalid use of ,string struct tag, trying to unmarshal "[1]" into int64`},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.input, func(t *testing.T) {
+			var v quotedFieldsStruct
+			err := Unmarshal([]byte(tc.input), &v)
+			if err == nil || !strings.Contains(err.Error(), tc.msg) {
+				t.Errorf("错误 = %v, 期望包含 %q", err, tc.msg)
+			}
+		})
+	}
+}
+
+// 测试解码经由嵌入指针提升的字段：按需分配指针，未导出的嵌入类型返回错误
+func TestUnmarshalEmbeddedPointer(t *testing.T) {
+	var v EmbedPtrModel
+	if err := Unmarshal([]byte(`{"id":7,"name":"n","title":"t"}`), &v); err != nil {
+		t.Fatalf("Unmarshal 失败: %v", err)
+	}
+	if v.EmbedBase == nil || v.ID != 7 || v.Name != "n" || v.Title != "t" {
+		t.Errorf("Unmarshal 结果 = %+v", v)
+	}
+	if v.embedInner != nil {
+		t.Error("未出现的嵌入字段不应被分配")
+	}
+
+	// 已有的嵌入指针被复用
+	base := &EmbedBase{ID: 1, Name: "keep"}
+	v = EmbedPtrModel{EmbedBase: base}
+	if err := Unmarshal([]byte(`{"id":2}`), &v); err != nil {
+		t.Fatalf("Unmarshal 失败: %v", err)
+	}
+	if v.EmbedBase != base || base.ID != 2 || base.Name != "keep" {
+		t.Errorf("应写入已有的嵌入指针: %+v", base)
+	}
+
+	v = EmbedPtrModel{}
+	err := Unmarshal([]byte(`{"title":"t","secret":"s"}`), &v)
+	if msg := "json: cannot set embedded pointer to unexported struct: sjson.embedInner"; err == nil || err.Error() != msg {
+		t.Errorf("错误 = %v, 期望 %q", err, msg)
+	}
+}
+
+// regHex 模拟无法为其添加 UnmarshalJSON 的第三方类型，JSON 中表示为十六进制字符串
+type regHex uint16
+
+// regNode 实现了 json.Unmarshaler，注册的解码函数应优先
+type regNode struct{ Name string }
+
+func (n *regNode) UnmarshalJSON([]byte) error {
+	n.Name = "unmarshaler"
+	return nil
+}
+
+// regGlobal 只在本测试中全局注册，避免影响其他测试
+type regGlobal string
+
+func decodeRegHex(ptr interface{}, r *Reader) error {
+	s, err := r.ReadString()
+	if err != nil {
+		return err
+	}
+	var n uint16
+	if _, err := fmt.Sscanf(s, "%x", &n); err != nil {
+		return err
+	}
+	*ptr.(*regHex) = regHex(n)
+	return nil
+}
+
+// 测试 RegisterTypeDecoder：注册的解码函数作用于各种位置，且优先于 Unmarshaler
+func TestRegisterTypeDecoder(t *testing.T) {
+	api := Config{}.Freeze()
+	api.RegisterTypeDecoder(reflect.TypeOf(regHex(0)), decodeRegHex)
+	api.RegisterTypeDecoder(reflect.TypeOf(regNode{}), func(ptr interface{}, r *Reader) error {
+		if r.ReadNull() {
+			ptr.(*regNode).Name = "null"
+			return nil
+		}
+		raw, err := r.ReadRaw()
+		ptr.(*regNode).Name = string(raw)
+		return err
+	})
+
+	var v struct {
+		Hex   regHex            `json:"hex"`
+		Hexes []regHex          `json:"hexes"`
+		Ptr   *regHex           `json:"ptr"`
+		Map   map[string]regHex `json:"map"`
+		Nodes []*regNode        `json:"nodes"`
+		Node  regNode           `json:"node"`
+	}
+	data := `{"hex":"ff","hexes":["1","a0"],"ptr":"10","map":{"k":"2"},"nodes":[{"a":1},null],"node":null}`
+	if err := api.Unmarshal([]byte(data), &v); err != nil {
+		t.Fatalf("Unmarshal 失败: %v", err)
+	}
+	if v.Hex != 0xff || !reflect.DeepEqual(v.Hexes, []regHex{1, 0xa0}) || v.Ptr == nil || *v.Ptr != 0x10 || v.Map["k"] != 2 {
+		t.Errorf("注册解码函数后结果错误: %+v", v)
+	}
+	if len(v.Nodes) != 2 || v.Nodes[0].Name != `{"a":1}` || v.Nodes[1] != nil || v.Node.Name != "null" {
+		t.Errorf("注册的解码函数应优先于 UnmarshalJSON: %+v, %+v", v.Nodes, v.Node)
+	}
+
+	// 流式解码同样生效
+	var hexes []regHex
+	if err := api.NewDecoder(strings.NewReader(`["c", "d"]`)).Decode(&hexes); err != nil || !reflect.DeepEqual(hexes, []regHex{0xc, 0xd}) {
+		t.Errorf("流式解码 = %v, %v", hexes, err)
+	}
+
+	// 未注册的 API 仍调用 UnmarshalJSON（包括 []*T 快速路径）
+	var nodes []*regNode
+	if err := Unmarshal([]byte(`[{"x":1}]`), &nodes); err != nil || nodes[0].Name != "unmarshaler" {
+		t.Errorf("未注册时应调用 UnmarshalJSON: %+v, %v", nodes, err)
+	}
+
+	// 解码函数的错误原样传出；没有读取任何值时报错
+	if err := api.Unmarshal([]byte(`{"hex":1}`), &v); err == nil {
+		t.Error("类型不匹配应返回错误")
+	}
+	lazy := Config{}.Freeze()
+	lazy.RegisterTypeDecoder(reflect.TypeOf(regHex(0)), func(interface{}, *Reader) error { return nil })
+	if err := lazy.Unmarshal([]byte(`["1"]`), &hexes); err == nil {
+		t.Error("解码函数没有读取值时应返回错误")
+	}
+
+	// 全局注册对包级函数与各 API 生效
+	RegisterTypeDecoder(reflect.TypeOf(regGlobal("")), func(ptr interface{}, r *Reader) error {
+		s, err := r.ReadString()
+		*ptr.(*regGlobal) = regGlobal(strings.ToUpper(s))
+		return err
+	})
+	var g []regGlobal
+	if err := Unmarshal([]byte(`["a"]`), &g); err != nil || g[0] != "A" {
+		t.Errorf("全局注册后 Unmarshal = %v, %v", g, err)
+	}
+	if err := api.Unmarshal([]byte(`["b"]`), &g); err != nil || g[0] != "B" {
+		t.Errorf("全局注册对 API 的 Unmarshal = %v, %v", g, err)
+	}
+}
+
+// 测试 FieldNamer 在解码时与编码对称
+func TestUnmarshalFieldNamer(t *testing.T) {
+	type model struct {
+		UserID    int
+		FirstName string
+		Tagged    string `json:"custom"`
+	}
+	config := Config{FieldNamer: SnakeCase}
+	var v model
+	if err := UnmarshalWithConfig([]byte(`{"user_id":7,"first_name":"a","custom":"c","UserID":9}`), &v, config); err != nil {
+		t.Fatalf("Unmarshal 失败: %v", err)
+	}
+	// Go 字段名不再是键名，只能以大小写不敏感方式匹配改写后的名字
+	if v != (model{UserID: 7, FirstName: "a", Tagged: "c"}) {
+		t.Errorf("SnakeCase 解码结果错误: %+v", v)
+	}
+
+	api := Config{FieldNamer: LowerCamel}.Freeze()
+	data, err := api.Marshal(model{UserID: 1, FirstName: "b"})
+	if err != nil {
+		t.Fatalf("Marshal 失败: %v", err)
+	}
+	var back model
+	if err := api.Unmarshal(data, &back); err != nil || back.UserID != 1 || back.FirstName != "b" {
+		t.Errorf("往返结果 %s -> %+v, %v", data, back, err)
+	}
+
+	// 未设置 FieldNamer 的默认配置不受影响
+	v = model{}
+	if err := Unmarshal([]byte(`{"user_id":7,"UserID":9}`), &v); err != nil || v.UserID != 9 {
+		t.Errorf("默认配置解码 = %+v, %v", v, err)
+	}
+}
+
+// decProgLevel 是没有自定义解码方式的具名整数，走偏移量写入
+type decProgLevel int8
+
+// decProgUpper 实现了 encoding.TextUnmarshaler，编译时应识别并走通用路径
+type decProgUpper string
+
+func (u *decProgUpper) UnmarshalText(b []byte) error {
+	*u = decProgUpper(strings.ToUpper(string(b)))
+	return nil
+}
+
+type decProgInner struct {
+	A int
+	B string
+}
+
+type decProgNode struct {
+	Name     string
+	Children []decProgNode
+	Next     *decProgNode
+}
+
+type decProgModel struct {
+	Bool     bool
+	I8       int8
+	I16      int16
+	I32      int32
+	I64      int64
+	U8       uint8
+	U16      uint16
+	U32      uint32
+	U64      uint64
+	Uint     uint
+	F32      float32
+	F64      float64
+	Str      string
+	Level    decProgLevel
+	Upper    decProgUpper
+	Ptr      *int
+	PtrPtr   **string
+	Inner    decProgInner
+	InnerPtr *decProgInner
+	Items    []decProgInner
+	Nums     []int32
+	Grid     [][]uint16
+	Ptrs     []*float64
+	Tags     []string
+	Any      interface{}
+	Map      map[string]int
+	Tree     decProgNode
+	Quoted   int64 `json:",string"`
+}
+
+// 测试结构体解码程序：各类字段按偏移量写入后与 encoding/json 的结果一致，
+// 回退到通用路径的情况（null、溢出、类型不匹配）语义不变
+func TestUnmarshalDecodeProgram(t *testing.T) {
+	inputs := []string{
+		`{"Bool":true,"I8":-128,"I16":32767,"I32":-5,"I64":9007199254740993,"U8":255,"U16":1,"U32":4294967295,
+		"U64":18446744073709551615,"Uint":7,"F32":1.5,"F64":-2.25e10,"Str":"s\n","Level":3,"Upper":"abc",
+		"Ptr":42,"PtrPtr":"pp","Inner":{"A":1,"B":"b"},"InnerPtr":{"B":"x"},
+		"Items":[{"A":1},{"A":2,"B":"c"},{},{"A":3},{"A":4},{"A":5},{"A":6},{"A":7},{"A":8},{"A":9}],
+		"Nums":[1,2,3],"Grid":[[1,2],[],[3]],"Ptrs":[1.5,null],"Tags":["a"],"Any":{"k":[1]},"Map":{"m":1},
+		"Tree":{"Name":"root","Children":[{"Name":"c1","Next":{"Name":"n"}}]},"Quoted":"12"}`,
+		`{"Ptr":null,"PtrPtr":null,"InnerPtr":null,"Items":null,"Nums":[],"Grid":null,"Inner":{},"i8":5}`,
+	}
+	for _, input := range inputs {
+		seed := func() decProgModel {
+			n, s := 1, "old"
+			ps := &s
+			return decProgModel{Ptr: &n, PtrPtr: &ps, InnerPtr: &decProgInner{A: 99}, Items: []decProgInner{{A: 1}}, Nums: []int32{9}}
+		}
+		got, want := seed(), seed()
+		if err := Unmarshal([]byte(input), &got); err != nil {
+			t.Fatalf("Unmarshal(%s) 失败: %v", input, err)
+		}
+		if err := json.Unmarshal([]byte(input), &want); err != nil {
+			t.Fatalf("json.Unmarshal(%s) 失败: %v", input, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Unmarshal(%s)\n得到 %+v\n期望 %+v", input, got, want)
+		}
+	}
+
+	errInputs := []string{
+		`{"I8":128}`,
+		`{"U8":-1}`,
+		`{"U16":65536}`,
+		`{"I32":1.5}`,
+		`{"Uint":-3}`,
+		`{"F32":1e40}`,
+		`{"Str":1}`,
+		`{"Bool":"true"}`,
+		`{"Nums":[1,"2"]}`,
+		`{"Items":[{"A":"x"}]}`,
+		`{"Inner":[1]}`,
+		`{"Grid":[[1],[2}`,
+	}
+	for _, input := range errInputs {
+		var v decProgModel
+		if err := Unmarshal([]byte(input), &v); err == nil {
+			t.Errorf("Unmarshal(%s) 应返回错误", input)
+		}
+	}
+
+	// 程序编译后注册的解码函数仍然生效
+	api := Config{}.Freeze()
+	var hex struct{ H regHex }
+	if err := api.Unmarshal([]byte(`{"H":16}`), &hex); err != nil || hex.H != 16 {
+		t.Fatalf("注册前解码 = %+v, %v", hex, err)
+	}
+	api.RegisterTypeDecoder(reflect.TypeOf(regHex(0)), decodeRegHex)
+	if err := api.Unmarshal([]byte(`{"H":"ff"}`), &hex); err != nil || hex.H != 0xff {
+		t.Errorf("注册后解码 = %+v, %v", hex, err)
+	}
+}
+
+// 测试字段为递归切片类型（recursiveTree、recursivePtrs）时解码与 encoding/json 一致
+func TestUnmarshalRecursiveSliceType(t *testing.T) {
+	type model struct {
+		T recursiveTree `json:"t"`
+		A recursivePtrs `json:"a"`
+	}
+	inputs := []string{
+		`{"t":[[]]}`,
+		`{"t":[null,[[],[[]]]],"a":[[],null,[null,[]]]}`,
+		`{"t":null,"a":[]}`,
+	}
+	for _, input := range inputs {
+		var got, expected model
+		err := Unmarshal([]byte(input), &got)
+		expectedErr := json.Unmarshal([]byte(input), &expected)
+		if (err != nil) != (expectedErr != nil) || !reflect.DeepEqual(got, expected) {
+			t.Errorf("Unmarshal(%s) = %#v, %v；期望 %#v, %v", input, got, err, expected, expectedErr)
+		}
+	}
+}
+
+// wideStructType 用 reflect.StructOf 构造有 n 个 int 字段的结构体，键名长短交替
+func wideStructType(n int) reflect.Type {
+	fields := make([]reflect.StructField, n)
+	for i := range fields {
+		name := fmt.Sprintf("f%d", i)
+		if i%2 == 1 {
+			name = fmt.Sprintf("event_attribute_%d", i)
+		}
+		fields[i] = reflect.StructField{
+			Name: fmt.Sprintf("F%d", i),
+			Type: reflect.TypeOf(0),
+			Tag:  reflect.StructTag(fmt.Sprintf(`json:"%s"`, name)),
+		}
+	}
+	return reflect.StructOf(fields)
+}
+
+// 测试字段查找表：各种宽度的结构体都能建表，精确、大小写不敏感与未知键的结果与线性扫描一致
+func TestStructFieldLookup(t *testing.T) {
+	for _, n := range []int{1, 2, 3, 8, 31, 32, 33, 128, 500} {
+		fields := defaultCodecs.getStructFields(wideStructType(n))
+		lookup := newFieldLookup(fields)
+		if lookup.exact == nil || lookup.folded == nil {
+			t.Fatalf("%d 个字段时未能建立查找表", n)
+		}
+		linear := fieldLookup{folds: lookup.folds}
+		for i := range fields {
+			name := fields[i].name
+			keys := [][]byte{name, bytes.ToUpper(name), append(append([]byte(nil), name...), 'x'), name[:len(name)-1]}
+			for _, key := range keys {
+				if got, want := lookup.find(fields, key), linear.find(fields, key); got != want {
+					t.Errorf("%d 个字段查找 %q = %d, 线性扫描 = %d", n, key, got, want)
+				}
+				if got, want := lookup.findFold(key), linear.findFold(key); got != want {
+					t.Errorf("%d 个字段折叠查找 %q = %d, 线性扫描 = %d", n, key, got, want)
+				}
+			}
+		}
+		if i := lookup.find(fields, nil); i != -1 {
+			t.Errorf("%d 个字段查找空键 = %d", n, i)
+		}
+	}
+
+	// 折叠后同名的字段：精确匹配各自命中，大小写不敏感匹配取声明在前的字段
+	type dup struct {
+		A int `json:"name"`
+		B int `json:"Name"`
+		C int `json:"NAME_"`
+	}
+	for _, input := range []string{`{"name":1,"Name":2}`, `{"NAME":3}`, `{"nAmE":4,"name_":5}`} {
+		var got, want dup
+		if err := Unmarshal([]byte(input), &got); err != nil {
+			t.Fatalf("Unmarshal(%s) 失败: %v", input, err)
+		}
+		_ = json.Unmarshal([]byte(input), &want)
+		if got != want {
+			t.Errorf("Unmarshal(%s) = %+v, 期望 %+v", input, got, want)
+		}
+	}
+
+	// 通过 reflect.StructOf 构造的宽结构体解码结果与 encoding/json 一致
+	typ := wideStructType(128)
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i := 0; i < 128; i++ {
+		name := typ.Field(i).Tag.Get("json")
+		if i%3 == 0 {
+			name = strings.ToUpper(name)
+		}
+		fmt.Fprintf(&buf, `"%s":%d,"unknown_%d":%d,`, name, i, i, i)
+	}
+	buf.Truncate(buf.Len() - 1)
+	buf.WriteByte('}')
+	got, want := reflect.New(typ), reflect.New(typ)
+	if err := Unmarshal(buf.Bytes(), got.Interface()); err != nil {
+		t.Fatalf("Unmarshal 失败: %v", err)
+	}
+	_ = json.Unmarshal(buf.Bytes(), want.Interface())
+	if !reflect.DeepEqual(got.Interface(), want.Interface()) {
+		t.Errorf("宽结构体解码结果与 encoding/json 不一致")
+	}
+}
+
+// foldModel 的键名含非 ASCII 字符，大小写不敏感匹配按 Unicode 简单折叠进行
+type foldModel struct {
+	Kelvin  int    `json:"k"`
+	Sophia  string `json:"σοφία"`
+	Strasse string
+	Name    string `json:"name"`
+}
+
+// 测试大小写不敏感匹配与 encoding/json 一致，包括非 ASCII 键
+func TestUnmarshalUnicodeFold(t *testing.T) {
+	inputs := []string{
+		`{"K":1}`,
+		`{"k":3,"K":2}`,
+		`{"ΣΟΦΊΑ":"upper"}`,
+		`{"σοφία":"exact","ΣΟΦΊΑ":"upper"}`,
+		`{"STRASSE":"ascii","ſtraſſe":"long-s"}`,
+		`{"NAME":"a","näme":"b","Kelvin":4}`,
+	}
+	for _, input := range inputs {
+		var got, want foldModel
+		if err := Unmarshal([]byte(input), &got); err != nil {
+			t.Fatalf("Unmarshal(%s) 失败: %v", input, err)
+		}
+		if err := json.Unmarshal([]byte(input), &want); err != nil {
+			t.Fatalf("json.Unmarshal(%s) 失败: %v", input, err)
+		}
+		if got != want {
+			t.Errorf("Unmarshal(%s) = %+v, 期望 %+v", input, got, want)
+		}
+	}
+}
+
+// 测试 CaseSensitive：只接受精确匹配的键，其余键按未知键跳过
+func TestUnmarshalCaseSensitive(t *testing.T) {
+	type model struct {
+		ID   int
+		Name string `json:"name"`
+	}
+	input := []byte(`{"ID":1,"id":2,"NAME":"x"}`)
+
+	var v model
+	if err := Unmarshal(input, &v); err != nil || v != (model{ID: 2, Name: "x"}) {
+		t.Errorf("默认配置解码 = %+v, %v", v, err)
+	}
+
+	api := Config{CaseSensitive: true}.Freeze()
+	v = model{}
+	if err := api.Unmarshal(input, &v); err != nil || v != (model{ID: 1}) {
+		t.Errorf("CaseSensitive 解码 = %+v, %v", v, err)
+	}
+	v = model{}
+	if err := UnmarshalWithConfig([]byte(`{"id":3,"name":"y","K":0}`), &v, Config{CaseSensitive: true}); err != nil || v != (model{Name: "y"}) {
+		t.Errorf("UnmarshalWithConfig 解码 = %+v, %v", v, err)
+	}
+
+	// 嵌套结构体与切片元素同样生效
+	var nested struct {
+		Items []model `json:"items"`
+	}
+	if err := api.Unmarshal([]byte(`{"ITEMS":[{"ID":1}],"items":[{"id":1,"ID":2}]}`), &nested); err != nil ||
+		len(nested.Items) != 1 || nested.Items[0].ID != 2 {
+		t.Errorf("CaseSensitive 嵌套解码 = %+v, %v", nested, err)
+	}
+}
+
+type unknownOwner struct {
+	Name string `json:"name"`
+}
+
+type unknownBase struct {
+	ID int `json:"id"`
+}
+
+type unknownItem struct {
+	SKU    string          `json:"sku"`
+	Owners []unknownOwner  `json:"owners"`
+	Refs   []*unknownOwner `json:"refs"`
+	Pair   [2]unknownOwner `json:"pair"`
+}
+
+type unknownRequest struct {
+	unknownBase
+	Username string                  `json:"username"`
+	Items    []unknownItem           `json:"items"`
+	Extra    map[string]unknownOwner `json:"extra"`
+}
+
+// 测试 DisallowUnknownFields：错误携带未知键、所在对象的路径与字节偏移，
+// 对嵌入结构体、嵌套切片、数组与 map 中的结构体都生效
+func TestUnmarshalDisallowUnknownFields(t *testing.T) {
+	tests := []struct {
+		input string
+		key   string
+		path  string
+	}{
+		{`{"id":1,"usrname":"x"}`, "usrname", ""},
+		{`{"items":[{"sku":"a"},{"owners":[{"name":"a"},{"nmae":"b"}]}]}`, "nmae", "items[1].owners[1]"},
+		{`{"items":[{"refs":[null,{"Name":"a","x":1}]}]}`, "x", "items[0].refs[1]"},
+		{`{"items":[{"pair":[{},{"n":1}]}]}`, "n", "items[0].pair[1]"},
+		{`{"extra":{"k":{"name":"a","age":3}}}`, "age", "extra.k"},
+		{`{"unknownBase":{"id":1}}`, "unknownBase", ""},
+	}
+	api := Config{DisallowUnknownFields: true}.Freeze()
+	for _, tc := range tests {
+		offset := int64(strings.Index(tc.input, `"`+tc.key+`"`))
+		check := func(name string, err error) {
+			var ue *UnknownFieldError
+			if !errors.As(err, &ue) {
+				t.Errorf("%s(%s) 错误 = %v, 期望 *UnknownFieldError", name, tc.input, err)
+				return
+			}
+			if ue.Key != tc.key || ue.Path != tc.path || ue.Offset != offset {
+				t.Errorf("%s(%s) = %+v, 期望 key=%q path=%q offset=%d", name, tc.input, *ue, tc.key, tc.path, offset)
+			}
+		}
+
+		var v unknownRequest
+		check("Unmarshal", api.Unmarshal([]byte(tc.input), &v))
+		check("UnmarshalWithConfig", UnmarshalWithConfig([]byte(tc.input), &v, Config{DisallowUnknownFields: true}))
+		dec := NewDecoder(iotest.OneByteReader(strings.NewReader(tc.input)))
+		dec.DisallowUnknownFields()
+		check("Decoder", dec.Decode(&v))
+
+		// 默认配置忽略未知键
+		if err := Unmarshal([]byte(tc.input), &v); err != nil {
+			t.Errorf("默认配置 Unmarshal(%s) 失败: %v", tc.input, err)
+		}
+	}
+
+	var v unknownRequest
+	err := api.Unmarshal([]byte(`{"items":[{"owners":[{"nmae":"b"}]}]}`), &v)
+	if err == nil || err.Error() != `json: unknown field "nmae" in "items[0].owners[0]"` {
+		t.Errorf("错误信息 = %v", err)
+	}
+	if err := api.Unmarshal([]byte(`{"id":1,"username":"u","items":[{"sku":"a","owners":[{"name":"o"}]}],"extra":{}}`), &v); err != nil {
+		t.Errorf("合法输入解码失败: %v", err)
+	}
+}
+
+// 测试 NumberMode：所有解码到 interface{} 的路径对数字采用相同的表示
+func TestUnmarshalNumberMode(t *testing.T) {
+	input := []byte(`{"big":9007199254740993,"f":1.5,"e":1e3,"huge":12345678901234567890,"arr":[-7],"obj":{"x":3}}`)
+	tests := []struct {
+		mode NumberMode
+		want map[string]interface{}
+	}{
+		{NumberAsFloat64, map[string]interface{}{
+			"big": float64(9007199254740993), "f": 1.5, "e": 1000.0, "huge": 12345678901234567890.0,
+			"arr": []interface{}{-7.0}, "obj": map[string]interface{}{"x": 3.0},
+		}},
+		{NumberAsNumber, map[string]interface{}{
+			"big": Number("9007199254740993"), "f": Number("1.5"), "e": Number("1e3"), "huge": Number("12345678901234567890"),
+			"arr": []interface{}{Number("-7")}, "obj": map[string]interface{}{"x": Number("3")},
+		}},
+		{NumberAsInt64WhenIntegral, map[string]interface{}{
+			"big": int64(9007199254740993), "f": 1.5, "e": 1000.0, "huge": 12345678901234567890.0,
+			"arr": []interface{}{int64(-7)}, "obj": map[string]interface{}{"x": int64(3)},
+		}},
+	}
+	for _, tc := range tests {
+		api := Config{NumberMode: tc.mode}.Freeze()
+
+		var m map[string]interface{}
+		if err := api.Unmarshal(input, &m); err != nil || !reflect.DeepEqual(m, tc.want) {
+			t.Errorf("模式 %d 解码 map = %#v, %v", tc.mode, m, err)
+		}
+		var i interface{}
+		if err := api.Unmarshal(input, &i); err != nil || !reflect.DeepEqual(i, tc.want) {
+			t.Errorf("模式 %d 解码 interface{} = %#v, %v", tc.mode, i, err)
+		}
+		var s struct {
+			Big interface{}   `json:"big"`
+			Arr []interface{} `json:"arr"`
+		}
+		if err := api.Unmarshal(input, &s); err != nil || s.Big != tc.want["big"] || !reflect.DeepEqual(s.Arr, tc.want["arr"]) {
+			t.Errorf("模式 %d 解码结构体 = %#v, %v", tc.mode, s, err)
+		}
+		var n interface{}
+		if err := api.Unmarshal([]byte(`9007199254740993`), &n); err != nil || n != tc.want["big"] {
+			t.Errorf("模式 %d 解码顶层数字 = %#v, %v", tc.mode, n, err)
+		}
+	}
+
+	// Decoder.UseNumber 与 NumberAsNumber 相同
+	dec := NewDecoder(strings.NewReader(`[9007199254740993]`))
+	dec.UseNumber()
+	var arr []interface{}
+	if err := dec.Decode(&arr); err != nil || !reflect.DeepEqual(arr, []interface{}{Number("9007199254740993")}) {
+		t.Errorf("UseNumber 解码 = %#v, %v", arr, err)
+	}
+}
+
+// 测试 Number 类型：方法、作为字段编解码，json.Number 与 encoding/json 行为一致
+func TestNumber(t *testing.T) {
+	n := Number("9007199254740993")
+	if i, err := n.Int64(); err != nil || i != 9007199254740993 {
+		t.Errorf("Int64() = %d, %v", i, err)
+	}
+	if f, err := Number("1.5e2").Float64(); err != nil || f != 150 {
+		t.Errorf("Float64() = %v, %v", f, err)
+	}
+	if _, err := Number("1.5").Int64(); err == nil {
+		t.Error("Int64() 对小数应返回错误")
+	}
+	if n.String() != "9007199254740993" {
+		t.Errorf("String() = %s", n.String())
+	}
+
+	type model struct {
+		N   Number      `json:"n"`
+		Q   Number      `json:"q,string"`
+		E   Number      `json:"e,omitempty"`
+		J   json.Number `json:"j"`
+		Any interface{} `json:"any"`
+	}
+	var v model
+	if err := Unmarshal([]byte(`{"n":-1.25E+10,"q":"42","j":"7","any":1}`), &v); err != nil {
+		t.Fatalf("Unmarshal 失败: %v", err)
+	}
+	if v.N != "-1.25E+10" || v.Q != "42" || v.J != "7" {
+		t.Errorf("Number 字段解码 = %+v", v)
+	}
+	for _, input := range []string{`{"n":"abc"}`, `{"n":"01"}`, `{"n":true}`} {
+		if err := Unmarshal([]byte(input), &model{}); err == nil {
+			t.Errorf("Unmarshal(%s) 应返回错误", input)
+		}
+	}
+
+	// Number 与 json.Number 的行为与 encoding/json 处理 json.Number 时一致
+	type stdModel struct {
+		J json.Number `json:"j"`
+		Q json.Number `json:"q,string"`
+		E json.Number `json:"e,omitempty"`
+	}
+	for _, input := range []string{`{"j":"abc"}`, `{"j":"01"}`, `{"j":"1."}`, `{"j":true}`, `{"j":-0.5e-3,"q":"12"}`, `{"q":"\"12\""}`} {
+		var got, want stdModel
+		err := Unmarshal([]byte(input), &got)
+		stdErr := json.Unmarshal([]byte(input), &want)
+		if (err == nil) != (stdErr == nil) || got != want {
+			t.Errorf("Unmarshal(%s) = %+v, %v, encoding/json = %+v, %v", input, got, err, want, stdErr)
+		}
+	}
+	for _, m := range []interface{}{stdModel{J: "1e5", Q: "3"}, []json.Number{"1", ""}, map[string]json.Number{"a": "2.5"}} {
+		got, err := Marshal(m)
+		want, _ := json.Marshal(m)
+		if err != nil || string(got) != string(want) {
+			t.Errorf("Marshal(%#v) = %s, %v, 期望 %s", m, got, err, want)
+		}
+	}
+
+	v.Any = Number("12345678901234567890")
+	want := `{"n":-1.25E+10,"q":"42","j":7,"any":12345678901234567890}`
+	for _, m := range []interface{}{v, &v} {
+		if got, err := Marshal(m); err != nil || string(got) != want {
+			t.Errorf("Marshal(%#v) = %s, %v, 期望 %s", m, got, err, want)
+		}
+	}
+	if got, err := Marshal([]Number{"1", ""}); err != nil || string(got) != "[1,0]" {
+		t.Errorf("Marshal([]Number) = %s, %v", got, err)
+	}
+	if _, err := Marshal(Number("1e")); err == nil {
+		t.Error("非法数字应返回错误")
+	}
+}
+
+// copyStringsModel 覆盖解码程序、map、[]string、interface{} 与 Number 中的字符串
+type copyStringsModel struct {
+	Name  string            `json:"name"`
+	Tags  []string          `json:"tags"`
+	Attrs map[string]string `json:"attrs"`
+	Any   interface{}       `json:"any"`
+	Num   Number            `json:"num"`
+	Inner struct {
+		Note string `json:"note"`
+	} `json:"inner"`
+}
+
+func TestUnmarshalCopyStrings(t *testing.T) {
+	const input = `{"name":"alice","tags":["a","b"],"attrs":{"k":"v"},"any":{"x":"y","n":[12]},"num":42,"inner":{"note":"hello"}}`
+	want := copyStringsModel{
+		Name:  "alice",
+		Tags:  []string{"a", "b"},
+		Attrs: map[string]string{"k": "v"},
+		Any:   map[string]interface{}{"x": "y", "n": []interface{}{12.0}},
+		Num:   "42",
+	}
+	want.Inner.Note = "hello"
+
+	// 默认复制：解码后覆盖输入缓冲区，已解码的值不变
+	buf := []byte(input)
+	var got copyStringsModel
+	if err := Unmarshal(buf, &got); err != nil {
+		t.Fatalf("解码失败: %v", err)
+	}
+	for i := range buf {
+		buf[i] = 'X'
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("复用输入后值被改变: %#v", got)
+	}
+
+	var m map[string]interface{}
+	buf = []byte(input)
+	if err := ConfigDefault.Unmarshal(buf, &m); err != nil {
+		t.Fatalf("解码失败: %v", err)
+	}
+	copy(buf, bytes.Repeat([]byte{'X'}, len(buf)))
+	if m["name"] != "alice" || !reflect.DeepEqual(m["attrs"], map[string]interface{}{"k": "v"}) {
+		t.Errorf("复用输入后 map 被改变: %#v", m)
+	}
+
+	// 长字符串单独分配，带转义的字符串本来就不引用输入
+	long := strings.Repeat("z", stringArenaMaxLen+1)
+	buf = []byte(`["` + long + `","a\nb"]`)
+	var ss []string
+	if err := Unmarshal(buf, &ss); err != nil {
+		t.Fatalf("解码失败: %v", err)
+	}
+	copy(buf, bytes.Repeat([]byte{'X'}, len(buf)))
+	if len(ss) != 2 || ss[0] != long || ss[1] != "a\nb" {
+		t.Errorf("复用输入后 []string 被改变: %q", ss)
+	}
+
+	// StringZeroCopy：未转义的字符串直接引用输入
+	buf = []byte(input)
+	var alias copyStringsModel
+	if err := UnmarshalWithConfig(buf, &alias, Config{CopyStrings: StringZeroCopy}); err != nil {
+		t.Fatalf("解码失败: %v", err)
+	}
+	copy(buf[bytes.Index(buf, []byte("alice")):], "ALICE")
+	if alias.Name != "ALICE" {
+		t.Errorf("StringZeroCopy 应引用输入，得到 %q", alias.Name)
+	}
+}
+
 // 基准测试比较旧的解析方式和新的直接解析方式
 func BenchmarkVsOldUnmarshal(b *testing.B) {
 	// 测试数据
@@ -390,3 +1184,320 @@ func compareValues(a, b interface{}) bool {
 
 	return a == b
 }
+
+// 测试 NewDecoder 依次解码多个顶层值，与 encoding/json.Decoder 的结果和偏移一致
+func TestDecoderStream(t *testing.T) {
+	input := `{"a":1,"b":[true,null]} [1,2,"x\"y"]
+"str" 3.5 -7 true null {"long":"` + strings.Repeat("长", 3000) + `"}  `
+
+	dec := NewDecoder(iotest.OneByteReader(strings.NewReader(input)))
+	std := json.NewDecoder(strings.NewReader(input))
+	for i := 0; ; i++ {
+		if dec.More() != std.More() {
+			t.Fatalf("第 %d 个值: More = %v, 期望 %v", i, dec.More(), std.More())
+		}
+		var got, expected interface{}
+		err := dec.Decode(&got)
+		stdErr := std.Decode(&expected)
+		if stdErr == io.EOF {
+			if err != io.EOF {
+				t.Fatalf("输入结束时 Decode = %v, 期望 io.EOF", err)
+			}
+			// 末尾的空白不计入偏移
+			if dec.InputOffset() != std.InputOffset() {
+				t.Errorf("输入结束时 InputOffset = %d, 期望 %d", dec.InputOffset(), std.InputOffset())
+			}
+			break
+		}
+		if err != nil {
+			t.Fatalf("第 %d 个值: Decode 失败: %v", i, err)
+		}
+		if !reflect.DeepEqual(got, expected) {
+			t.Errorf("第 %d 个值: %v, 期望 %v", i, got, expected)
+		}
+		if dec.InputOffset() != std.InputOffset() {
+			t.Errorf("第 %d 个值: InputOffset = %d, 期望 %d", i, dec.InputOffset(), std.InputOffset())
+		}
+	}
+	if err := dec.Decode(new(interface{})); err != io.EOF {
+		t.Errorf("再次 Decode = %v, 期望 io.EOF", err)
+	}
+
+	// 逐个读取标记时，输入结束后的偏移同样停在最后一个标记之后
+	dec = NewDecoder(strings.NewReader("[1, 2 ]\n"))
+	std = json.NewDecoder(strings.NewReader("[1, 2 ]\n"))
+	for {
+		_, err := dec.Token()
+		_, stdErr := std.Token()
+		if err != stdErr {
+			t.Fatalf("Token 错误 = %v, 期望 %v", err, stdErr)
+		}
+		if dec.InputOffset() != std.InputOffset() {
+			t.Errorf("Token 之后 InputOffset = %d, 期望 %d", dec.InputOffset(), std.InputOffset())
+		}
+		if err != nil {
+			break
+		}
+	}
+
+	// Buffered 返回第一个值之后已读入的数据
+	dec = NewDecoder(strings.NewReader(`{"a":1} tail`))
+	var m map[string]int
+	if err := dec.Decode(&m); err != nil {
+		t.Fatalf("Decode 失败: %v", err)
+	}
+	if rest, _ := io.ReadAll(dec.Buffered()); string(rest) != " tail" {
+		t.Errorf("Buffered = %q, 期望 %q", rest, " tail")
+	}
+}
+
+// 测试跨越窗口边界的字符串、跳过的字段和 Unmarshaler 原始值
+func TestDecoderStreamLarge(t *testing.T) {
+	type item struct {
+		ID   int             `json:"id"`
+		Name string          `json:"name"`
+		Raw  json.RawMessage `json:"raw"`
+	}
+	items := make([]map[string]interface{}, 2000)
+	for i := range items {
+		items[i] = map[string]interface{}{
+			"id":      i,
+			"name":    fmt.Sprintf("名字\t%d \"q\" \\u 😀", i),
+			"raw":     map[string]interface{}{"nested": []interface{}{i, "s]}"}},
+			"ignored": []interface{}{map[string]interface{}{"x": "}]"}, 1.5},
+		}
+	}
+	data, _ := json.Marshal(items)
+
+	var got, expected []item
+	if err := UnmarshalFromReader(iotest.HalfReader(bytes.NewReader(data)), &got); err != nil {
+		t.Fatalf("UnmarshalFromReader 失败: %v", err)
+	}
+	if err := json.Unmarshal(data, &expected); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("流式解码结果与 encoding/json 不一致")
+	}
+}
+
+// 测试 Decode 读完一个值后立即返回，不等待后续输入
+func TestDecoderNoReadAhead(t *testing.T) {
+	r, w := io.Pipe()
+	defer w.Close()
+	go w.Write([]byte(`{"n":1}` + "\n"))
+
+	done := make(chan error, 1)
+	go func() {
+		var v map[string]int
+		done <- NewDecoder(r).Decode(&v)
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Decode 失败: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Decode 在值结束后仍在等待输入")
+	}
+}
+
+func TestDecoderStreamErrors(t *testing.T) {
+	var v interface{}
+	if err := NewDecoder(strings.NewReader(`{"a":[1,2`)).Decode(&v); err != io.ErrUnexpectedEOF {
+		t.Errorf("截断输入: %v, 期望 io.ErrUnexpectedEOF", err)
+	}
+	// 值在记号中间被截断
+	for _, input := range []string{`["abc`, `[tru`, `[1.`, `{"a":"\u00`, `"x\`} {
+		if err := NewDecoder(strings.NewReader(input)).Decode(&v); err != io.ErrUnexpectedEOF {
+			t.Errorf("截断的记号 %s: %v, 期望 io.ErrUnexpectedEOF", input, err)
+		}
+	}
+	if err := NewDecoder(strings.NewReader("  ")).Decode(&v); err != io.EOF {
+		t.Errorf("空输入: %v, 期望 io.EOF", err)
+	}
+
+	readErr := errors.New("boom")
+	dec := NewDecoder(iotest.DataErrReader(io.MultiReader(strings.NewReader(`[1,`), iotest.ErrReader(readErr))))
+	if err := dec.Decode(&v); err != readErr {
+		t.Errorf("读取错误: %v, 期望 %v", err, readErr)
+	}
+	if err := dec.Decode(&v); err != readErr {
+		t.Errorf("错误应保持, 得到 %v", err)
+	}
+
+	if err := UnmarshalFromReader(strings.NewReader(`{"a":1} {}`), &v); err == nil {
+		t.Error("UnmarshalFromReader 应拒绝多余内容")
+	}
+	if err := UnmarshalFromReader(strings.NewReader(``), &v); err != io.ErrUnexpectedEOF {
+		t.Errorf("UnmarshalFromReader 空输入: %v", err)
+	}
+}
+
+// tokenString 把 Token 转成便于比较的文本：分隔符原样，键加 "key:" 前缀，值为原始 JSON
+func tokenString(tok Token) string {
+	switch tok.Type {
+	case StringToken:
+		b, _ := json.Marshal(string(tok.Value))
+		if tok.IsKey {
+			return "key:" + string(b)
+		}
+		return string(b)
+	default:
+		return string(tok.Value)
+	}
+}
+
+// 测试 Token 与 encoding/json.Decoder.Token 返回相同的标记序列
+func TestDecoderToken(t *testing.T) {
+	input := ` {"a": [1, -2.5, "s", true, false, null], "b": {"c": {}, "d": []}} [] "x\n"`
+
+	var got []string
+	dec := NewDecoder(iotest.OneByteReader(strings.NewReader(input)))
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Token 失败: %v", err)
+		}
+		got = append(got, tokenString(tok))
+	}
+
+	var expected []string
+	std := json.NewDecoder(strings.NewReader(input))
+	for {
+		tok, err := std.Token()
+		if err == io.EOF {
+			break
+		}
+		switch v := tok.(type) {
+		case json.Delim:
+			expected = append(expected, v.String())
+		case string:
+			b, _ := json.Marshal(v)
+			// encoding/json 不区分键和值，这里根据下一个字符判断
+			if std.More() && tokenIsKey(input, std.InputOffset()) {
+				expected = append(expected, "key:"+string(b))
+			} else {
+				expected = append(expected, string(b))
+			}
+		case nil:
+			expected = append(expected, "null")
+		default:
+			b, _ := json.Marshal(v)
+			expected = append(expected, string(b))
+		}
+	}
+
+	if strings.Join(got, " ") != strings.Join(expected, " ") {
+		t.Errorf("Token 序列 = %v, 期望 %v", got, expected)
+	}
+}
+
+// tokenIsKey 报告 offset 之后的第一个非空白字符是否为冒号
+func tokenIsKey(input string, offset int64) bool {
+	return strings.HasPrefix(strings.TrimLeft(input[offset:], " \t\r\n"), ":")
+}
+
+// 测试 Token 与 Decode 交替调用，逐个解码大数组的元素
+func TestDecoderTokenDecode(t *testing.T) {
+	input := `{"items": [{"name": "a", "age": 1}, {"name": "b", "age": 2}], "total": 2}`
+	dec := NewDecoder(strings.NewReader(input))
+
+	type person struct {
+		Name string `json:"name"`
+		Age  int    `json:"age"`
+	}
+	expectToken := func(want string) {
+		t.Helper()
+		tok, err := dec.Token()
+		if err != nil || tokenString(tok) != want {
+			t.Fatalf("Token = %s, %v, 期望 %s", tokenString(tok), err, want)
+		}
+	}
+
+	expectToken("{")
+	expectToken(`key:"items"`)
+	expectToken("[")
+	var items []person
+	for dec.More() {
+		var item person
+		if err := dec.Decode(&item); err != nil {
+			t.Fatalf("Decode 元素失败: %v", err)
+		}
+		items = append(items, item)
+	}
+	expectToken("]")
+	expectToken(`key:"total"`)
+	var total int
+	if err := dec.Decode(&total); err != nil {
+		t.Fatalf("Decode total 失败: %v", err)
+	}
+	expectToken("}")
+	if _, err := dec.Token(); err != io.EOF {
+		t.Errorf("输入结束时 Token = %v, 期望 io.EOF", err)
+	}
+
+	if len(items) != 2 || items[1].Name != "b" || items[1].Age != 2 || total != 2 {
+		t.Errorf("解码结果 = %+v, total = %d", items, total)
+	}
+}
+
+func TestDecoderTokenErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		err   string
+	}{
+		{`[1 2]`, "invalid character '2' after array value"},
+		{`{"a" 1}`, "invalid character '1' after object key"},
+		{`{1: 2}`, "invalid character '1' looking for beginning of object key string"},
+		{`{"a": 1 "b": 2}`, `invalid character '"' after object key:value pair`},
+		{`[1,]`, "invalid character ']' looking for beginning of value"},
+		{`[}`, "invalid character '}' looking for beginning of value"},
+		{`[1, 2`, io.ErrUnexpectedEOF.Error()},
+		// 记号被输入结尾截断
+		{`["abc`, io.ErrUnexpectedEOF.Error()},
+		{`[tru`, io.ErrUnexpectedEOF.Error()},
+		{`[1.`, io.ErrUnexpectedEOF.Error()},
+		{`["\u12`, io.ErrUnexpectedEOF.Error()},
+		{`{"a":-`, io.ErrUnexpectedEOF.Error()},
+		// 记号中间的非法字节
+		{`["a\x"]`, "invalid character 'x' in string escape code"},
+		{`["\u12g4"]`, "invalid character 'g' in \\u hexadecimal character escape"},
+		{`[nulx]`, "invalid character 'x' in literal null (expecting 'l')"},
+		{`[1.x]`, "invalid character 'x' after decimal point in numeric literal"},
+		{"[\"a\x01\"]", `invalid character '\x01' in string`},
+	}
+	for _, tc := range tests {
+		dec := NewDecoder(strings.NewReader(tc.input))
+		var err error
+		for err == nil {
+			_, err = dec.Token()
+		}
+		if err.Error() != tc.err {
+			t.Errorf("Token(%s) 错误 = %q, 期望 %q", tc.input, err, tc.err)
+		}
+	}
+
+	// 偏移量指向出错的字节而不是记号开头
+	dec := NewDecoder(strings.NewReader(`[1, "a\\b\x"]`))
+	var err error
+	for err == nil {
+		_, err = dec.Token()
+	}
+	var se *SyntaxError
+	if !errors.As(err, &se) || se.Offset != 10 {
+		t.Errorf("非法转义的错误 = %v, 期望偏移 10", err)
+	}
+
+	// Decode 在键之后必须遇到冒号
+	dec = NewDecoder(strings.NewReader(`{"a" 1}`))
+	dec.Token()
+	dec.Token()
+	var v int
+	if err := dec.Decode(&v); err == nil || err.Error() != "invalid character '1' after object key" {
+		t.Errorf("Decode 错误 = %v", err)
+	}
+}
@@ -0,0 +1,224 @@
+package sjson
+
+import "io"
+
+// tokenState 记录 Decoder.Token 在当前嵌套层中的位置，取值与 encoding/json 相同
+type tokenState int
+
+const (
+	tokenTopValue    tokenState = iota
+	tokenArrayStart             // 刚读入 '['
+	tokenArrayValue             // 逗号之后，期待元素
+	tokenArrayComma             // 元素之后，期待 ',' 或 ']'
+	tokenObjectStart            // 刚读入 '{'
+	tokenObjectKey              // 逗号之后，期待键
+	tokenObjectColon            // 键之后，期待 ':'
+	tokenObjectValue            // 冒号之后，期待值
+	tokenObjectComma            // 值之后，期待 ',' 或 '}'
+)
+
+// Token 返回输入中的下一个标记，用于逐个元素地拉取解析大数组等场景，对应 encoding/json.Decoder.Token。
+// 逗号和冒号会被校验并跳过，不会返回；对象键以 IsKey 为 true 的 StringToken 返回。
+// 输入结束时返回 io.EOF，在数组或对象内部结束时返回 io.ErrUnexpectedEOF。
+// Token 可以与 Decode 交替调用，例如读入 '[' 后用 Decode 逐个解码元素。
+func (d *Decoder) Token() (Token, error) {
+	if d.err != nil {
+		return Token{}, d.err
+	}
+	for {
+		d.lexer.valueDone = false
+		tok := d.lexer.nextStreamToken()
+		switch tok.Type {
+		case LeftBracketToken:
+			if !d.tokenValueAllowed() {
+				return d.tokenError(tok)
+			}
+			d.tokenStack = append(d.tokenStack, d.tokenState)
+			d.tokenState = tokenArrayStart
+			return tok, nil
+
+		case RightBracketToken:
+			if d.tokenState != tokenArrayStart && d.tokenState != tokenArrayComma {
+				return d.tokenError(tok)
+			}
+			d.popTokenState()
+			return tok, nil
+
+		case LeftBraceToken:
+			if !d.tokenValueAllowed() {
+				return d.tokenError(tok)
+			}
+			d.tokenStack = append(d.tokenStack, d.tokenState)
+			d.tokenState = tokenObjectStart
+			return tok, nil
+
+		case RightBraceToken:
+			if d.tokenState != tokenObjectStart && d.tokenState != tokenObjectComma {
+				return d.tokenError(tok)
+			}
+			d.popTokenState()
+			return tok, nil
+
+		case ColonToken:
+			if d.tokenState != tokenObjectColon {
+				return d.tokenError(tok)
+			}
+			d.tokenState = tokenObjectValue
+
+		case CommaToken:
+			switch d.tokenState {
+			case tokenArrayComma:
+				d.tokenState = tokenArrayValue
+			case tokenObjectComma:
+				d.tokenState = tokenObjectKey
+			default:
+				return d.tokenError(tok)
+			}
+
+		case StringToken:
+			if d.tokenState == tokenObjectStart || d.tokenState == tokenObjectKey {
+				d.tokenState = tokenObjectColon
+				tok.IsKey = true
+				return tok, nil
+			}
+			if !d.tokenValueAllowed() {
+				return d.tokenError(tok)
+			}
+			d.tokenValueEnd()
+			return tok, nil
+
+		case IntegerToken, FloatToken, TrueToken, FalseToken, NullToken:
+			if !d.tokenValueAllowed() {
+				return d.tokenError(tok)
+			}
+			d.tokenValueEnd()
+			return tok, nil
+
+		case EOFToken:
+			err := d.lexer.readErr()
+			if err == io.EOF && (len(d.tokenStack) > 0 || d.tokenState != tokenTopValue) {
+				err = io.ErrUnexpectedEOF
+			}
+			d.err = err
+			return Token{}, err
+
+		default:
+			return d.tokenError(tok)
+		}
+	}
+}
+
+// tokenValueAllowed 报告当前位置是否可以开始一个值
+func (d *Decoder) tokenValueAllowed() bool {
+	switch d.tokenState {
+	case tokenTopValue, tokenArrayStart, tokenArrayValue, tokenObjectValue:
+		return true
+	}
+	return false
+}
+
+// tokenValueEnd 在一个完整的值（标量或闭合的数组、对象）之后更新状态
+func (d *Decoder) tokenValueEnd() {
+	switch d.tokenState {
+	case tokenArrayStart, tokenArrayValue:
+		d.tokenState = tokenArrayComma
+	case tokenObjectValue:
+		d.tokenState = tokenObjectComma
+	}
+}
+
+// popTokenState 离开当前数组或对象，回到外层
+func (d *Decoder) popTokenState() {
+	n := len(d.tokenStack) - 1
+	d.tokenState = d.tokenStack[n]
+	d.tokenStack = d.tokenStack[:n]
+	d.tokenValueEnd()
+}
+
+// tokenPrepareForDecode 在 Token 之后调用 Decode 时，先消费元素之间的逗号或键之后的冒号
+func (d *Decoder) tokenPrepareForDecode() error {
+	switch d.tokenState {
+	case tokenArrayComma:
+		d.lexer.valueDone = false
+		if tok := d.lexer.NextToken(); tok.Type != CommaToken {
+			_, err := d.tokenError(tok)
+			return err
+		}
+		d.tokenState = tokenArrayValue
+	case tokenObjectColon:
+		d.lexer.valueDone = false
+		if tok := d.lexer.NextToken(); tok.Type != ColonToken {
+			_, err := d.tokenError(tok)
+			return err
+		}
+		d.tokenState = tokenObjectValue
+	}
+	return nil
+}
+
+// tokenError 返回与 encoding/json 措辞相同的 *SyntaxError，错误是粘滞的
+func (d *Decoder) tokenError(tok Token) (Token, error) {
+	if tok.Type == EOFToken {
+		d.err = io.ErrUnexpectedEOF
+		if err := d.lexer.readErr(); err != io.EOF {
+			d.err = err
+		}
+		return Token{}, d.err
+	}
+
+	var context string
+	switch d.tokenState {
+	case tokenTopValue, tokenArrayStart, tokenArrayValue, tokenObjectValue:
+		context = " looking for beginning of value"
+	case tokenArrayComma:
+		context = " after array value"
+	case tokenObjectStart, tokenObjectKey:
+		context = " looking for beginning of object key string"
+	case tokenObjectColon:
+		context = " after object key"
+	case tokenObjectComma:
+		context = " after object key:value pair"
+	}
+	if tok.Type == InvalidToken {
+		d.err = d.invalidTokenError(tok, context)
+		return Token{}, d.err
+	}
+	c := d.lexer.input[tok.Pos]
+	d.err = &SyntaxError{msg: "invalid character " + quoteChar(c) + context, Offset: d.lexer.offset + int64(tok.Pos)}
+	return Token{}, d.err
+}
+
+// invalidTokenError 重新扫描无效记号，找出真正出错的位置：记号被输入结尾截断（如 "abc、tru、1.）时
+// 返回 io.ErrUnexpectedEOF（读取出错时返回该错误）；否则返回指向出错字节的 *SyntaxError，
+// 第一个字节就不能开始一个值时使用 context 作为上下文
+func (d *Decoder) invalidTokenError(tok Token, context string) error {
+	r := reformatter{src: d.lexer.input[tok.Pos:]}
+	var err error
+	switch c := r.src[0]; c {
+	case '"':
+		err = r.copyString()
+	case '-', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
+		err = r.copyNumber()
+	case 't':
+		err = r.copyLiteral("true")
+	case 'f':
+		err = r.copyLiteral("false")
+	case 'n':
+		err = r.copyLiteral("null")
+	default:
+		return &SyntaxError{msg: "invalid character " + quoteChar(c) + context, Offset: d.lexer.offset + int64(tok.Pos)}
+	}
+
+	switch {
+	case err == nil:
+		// 语法正确但无法表示的记号（如超出 float64 范围的数字），沿用词法分析器的描述
+		return &SyntaxError{msg: string(tok.Value), Offset: d.lexer.offset + int64(tok.Pos)}
+	case r.pos >= len(r.src):
+		if rerr := d.lexer.readErr(); rerr != io.EOF {
+			return rerr
+		}
+		return io.ErrUnexpectedEOF
+	}
+	err.(*SyntaxError).Offset = d.lexer.offset + int64(tok.Pos+r.pos)
+	return err
+}
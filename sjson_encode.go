@@ -3,7 +3,9 @@ package sjson
 import (
 	"encoding"
 	"encoding/json"
+	"fmt"
 	"reflect"
+	"strconv"
 	"sync"
 )
 
@@ -37,12 +39,12 @@ type Encoder interface {
 	appendToBytes(*encoderStream, reflect.Value) error
 }
 
-// 直接编码器缓存
+// 直接编码器缓存（默认配置使用的 defaultCodecs.encoders）
 var EncoderCache sync.Map // map[reflect.Type]Encoder
 
-// 使用小对象缓存池，避免频繁创建编码器实例
+// 使用小对象缓存池，避免频繁创建编码器实例。
+// sliceEncoder / ptrEncoder 只记录元素类型，运行时从 stream.codecs 取元素编码器，可以在各缓存间共享
 var sliceEncoderPool sync.Map
-var mapEncoderPool sync.Map
 var ptrEncoderPool sync.Map
 
 // json.Marshaler / encoding.TextMarshaler 接口类型，用于编码器构建时的静态检查
@@ -51,6 +53,58 @@ var (
 	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
 )
 
+// UnsupportedValueError 表示编码时遇到了无法用 JSON 表示的值（如 NaN、±Inf）。
+// Path 记录出错值在文档中的位置，例如 "items[3].price"；顶层值出错时为空。
+// 环引用在嵌套上千层后才被发现，此时的路径只是同一段的重复，因此不记录 Path。
+type UnsupportedValueError struct {
+	Value reflect.Value
+	Str   string
+	Path  string
+
+	noPath bool // 不补全路径（环引用）
+}
+
+func (e *UnsupportedValueError) Error() string {
+	if e.Path == "" {
+		return "json: unsupported value: " + e.Str
+	}
+	return "json: unsupported value: " + e.Str + " at " + strconv.Quote(e.Path)
+}
+
+// InvalidUTF8Error 表示在 InvalidUTF8Reject 模式下遇到了含非法 UTF-8 的字符串
+type InvalidUTF8Error struct {
+	S string // 出错的完整字符串
+}
+
+func (e *InvalidUTF8Error) Error() string {
+	return "json: invalid UTF-8 in string: " + strconv.Quote(e.S)
+}
+
+// withFieldPath 在错误向上传播时为 UnsupportedValueError 补全字段路径，
+// 只在出错路径上执行，不影响正常编码的开销
+func withFieldPath(err error, name string) error {
+	if e, ok := err.(*UnsupportedValueError); ok && !e.noPath {
+		if e.Path == "" || e.Path[0] == '[' {
+			e.Path = name + e.Path
+		} else {
+			e.Path = name + "." + e.Path
+		}
+	}
+	return err
+}
+
+// withIndexPath 为数组/切片元素补全下标路径
+func withIndexPath(err error, i int) error {
+	if e, ok := err.(*UnsupportedValueError); ok && !e.noPath {
+		if e.Path == "" || e.Path[0] == '[' {
+			e.Path = "[" + strconv.Itoa(i) + "]" + e.Path
+		} else {
+			e.Path = "[" + strconv.Itoa(i) + "]." + e.Path
+		}
+	}
+	return err
+}
+
 // jsonMarshalerEncoder 用于类型本身（值接收者或指针类型）实现 json.Marshaler 的情况
 type jsonMarshalerEncoder struct{}
 
@@ -64,8 +118,7 @@ func (e jsonMarshalerEncoder) appendToBytes(stream *encoderStream, src reflect.V
 	if err != nil {
 		return err
 	}
-	stream.buffer = append(stream.buffer, data...)
-	return nil
+	return appendMarshalJSON(stream, src.Type(), data)
 }
 
 // addrJSONMarshalerEncoder 用于仅指针接收者实现 json.Marshaler 的情况（值本身不可寻址时回退到基础编码器）
@@ -78,10 +131,38 @@ func (e addrJSONMarshalerEncoder) appendToBytes(stream *encoderStream, src refle
 		if err != nil {
 			return err
 		}
+		return appendMarshalJSON(stream, src.Type(), data)
+	}
+	return e.fallback.appendToBytes(stream, src)
+}
+
+// appendMarshalJSON 写入 MarshalJSON 的输出，出错时带上类型信息（与 encoding/json 一致）
+func appendMarshalJSON(stream *encoderStream, t reflect.Type, data []byte) error {
+	if err := appendRawJSON(stream, data); err != nil {
+		return fmt.Errorf("json: error calling MarshalJSON for type %v: %w", t, err)
+	}
+	return nil
+}
+
+// appendRawJSON 写入一段外部给出的 JSON 文本。默认原样写入；缩进模式下按当前层级重新缩进，
+// 开启 EscapeHTML / EscapeLineTerminators 时压缩并补充转义
+func appendRawJSON(stream *encoderStream, data []byte) error {
+	escape := stream.escape & (escapeHTML | escapeLineTerminators)
+	if !stream.indenting && escape == 0 {
 		stream.buffer = append(stream.buffer, data...)
 		return nil
 	}
-	return e.fallback.appendToBytes(stream, src)
+	r := reformatter{dst: stream.buffer, src: data, escape: escape}
+	if stream.indenting {
+		r.indenting = true
+		r.prefix, r.indent, r.depth = stream.prefix, stream.indent, stream.depth
+	}
+	buf, err := r.run()
+	if err != nil {
+		return err
+	}
+	stream.buffer = buf
+	return nil
 }
 
 // jsonTextMarshalerEncoder 用于类型本身实现 encoding.TextMarshaler 的情况
@@ -123,8 +204,11 @@ func encodeValueToBytes(stream *encoderStream, src reflect.Value, typ reflect.Ty
 		stream.buffer = append(stream.buffer, nullString...)
 		return nil
 	}
+	if stream.config.Canonical {
+		return encodeCanonical(stream, src, typ)
+	}
 
-	encoder := getEncoder(typ)
+	encoder := stream.codecs.getEncoder(typ)
 	return encoder.appendToBytes(stream, src)
 }
 
@@ -149,10 +233,53 @@ func isEmptyValue(v reflect.Value) bool {
 	return false
 }
 
+// isZeroer 即 omitzero 使用的 IsZero() bool 方法（如 time.Time）
+type isZeroer interface {
+	IsZero() bool
+}
+
+var isZeroerType = reflect.TypeOf((*isZeroer)(nil)).Elem()
+
+// isZeroFunc 为 omitzero 字段返回调用其 IsZero 方法的判断函数，规则与 encoding/json 相同：
+// nil 接口、nil 指针视为零值；仅指针接收者实现时，不可寻址的值先复制一份再取地址。
+// 类型没有 IsZero 方法时返回 nil，由调用方按 reflect.Value.IsZero 判断
+func isZeroFunc(t reflect.Type, omitzero bool) func(reflect.Value) bool {
+	if !omitzero {
+		return nil
+	}
+	switch {
+	case t.Kind() == reflect.Interface && t.Implements(isZeroerType):
+		return func(v reflect.Value) bool {
+			return v.IsNil() || (v.Elem().Kind() == reflect.Ptr && v.Elem().IsNil()) || v.Interface().(isZeroer).IsZero()
+		}
+	case t.Kind() == reflect.Ptr && t.Implements(isZeroerType):
+		return func(v reflect.Value) bool {
+			return v.IsNil() || v.Interface().(isZeroer).IsZero()
+		}
+	case t.Implements(isZeroerType):
+		return func(v reflect.Value) bool {
+			return v.Interface().(isZeroer).IsZero()
+		}
+	case reflect.PointerTo(t).Implements(isZeroerType):
+		return func(v reflect.Value) bool {
+			if !v.CanAddr() {
+				v2 := reflect.New(v.Type()).Elem()
+				v2.Set(v)
+				v = v2
+			}
+			return v.Addr().Interface().(isZeroer).IsZero()
+		}
+	}
+	return nil
+}
+
 // 根据类型获取直接编码器
 // 快速路径编码器获取，减少反射和缓存查找开销
-func getEncoderFast(t reflect.Type) Encoder {
-	// 使用原子操作的快速路径检查常用类型
+func (c *codecCache) getEncoderFast(t reflect.Type) Encoder {
+	// 具名类型可能实现了 Marshaler 或注册了编码函数，只有未命名类型能按 Kind 直接分派
+	if t.PkgPath() != "" {
+		return c.getEncoder(t)
+	}
 	switch t.Kind() {
 	case reflect.String:
 		return stringEncoderInst
@@ -169,55 +296,92 @@ func getEncoderFast(t reflect.Type) Encoder {
 	case reflect.Interface:
 		return interfaceEncoderInst
 	default:
-		return getEncoder(t) // 回退到完整实现
+		return c.getEncoder(t) // 回退到完整实现
 	}
 }
 
-func getEncoder(t reflect.Type) Encoder {
+func (c *codecCache) getEncoder(t reflect.Type) Encoder {
 	if t == nil {
 		return nullEncoder{}
 	}
 
 	// 检查缓存
-	if enc, ok := EncoderCache.Load(t); ok {
+	if enc, ok := c.encoders.Load(t); ok {
 		return enc.(Encoder)
 	}
 
-	var enc Encoder
+	// 递归类型（如 type Node struct{ Children map[string]Node }）在构建过程中会再次请求自身的编码器，
+	// 先放入一个占位的间接编码器，递归请求拿到占位符即可返回；构建完成后再替换为真正的编码器
+	ie := &indirectEncoder{}
+	ie.wg.Add(1)
+	if cached, loaded := c.encoders.LoadOrStore(t, ie); loaded {
+		return cached.(Encoder)
+	}
+	// buildEncoder 可能 panic（如自定义 FieldNamer）：仍要唤醒等待占位符的 goroutine，
+	// 并移除占位符，之后的请求重新构建
+	defer func() {
+		if ie.enc == nil {
+			c.encoders.CompareAndDelete(t, ie)
+		}
+		ie.wg.Done()
+	}()
+
+	enc := c.buildEncoder(t)
+	ie.enc = enc
+	c.encoders.Store(t, enc)
+	return enc
+}
+
+// indirectEncoder 是递归类型构建期间的占位编码器，等待真正的编码器构建完成后转发调用
+type indirectEncoder struct {
+	wg  sync.WaitGroup
+	enc Encoder
+}
+
+func (e *indirectEncoder) appendToBytes(stream *encoderStream, src reflect.Value) error {
+	e.wg.Wait()
+	if e.enc == nil {
+		return fmt.Errorf("json: failed to build encoder for %v", src.Type())
+	}
+	return e.enc.appendToBytes(stream, src)
+}
+
+// buildEncoder 为类型构建编码器（不读写缓存，由 getEncoder 负责缓存）
+func (c *codecCache) buildEncoder(t reflect.Type) Encoder {
+	// 注册的编码函数优先于一切接口检查
+	if fn := c.typeEncoder(t); fn != nil {
+		return typeFuncEncoder{fn: fn}
+	}
+	// *T 的方法集包含 T 的值接收者方法（如 *time.Time 的 MarshalJSON）：T 注册了编码函数时，
+	// 指针由 ptrEncoder 处理 nil 后交给该函数，不能被下面的 Marshaler 检查截走
+	if t.Kind() == reflect.Ptr && c.typeEncoder(t.Elem()) != nil {
+		return ptrEncoder{elemType: t.Elem()}
+	}
+	if isNumberType(t) {
+		return numberEncoder{}
+	}
 
 	// json.Marshaler / encoding.TextMarshaler 检查：
 	// 类型本身或其指针类型实现了这些接口时，编码必须调用对应方法，而不能走默认反射编码
 	// （time.Time 等标准库类型即依赖此机制）
 	if t.Implements(jsonMarshalerType) {
-		enc = jsonMarshalerEncoder{}
-		EncoderCache.Store(t, enc)
-		return enc
+		return jsonMarshalerEncoder{}
 	}
 	if t.Kind() != reflect.Ptr && reflect.PointerTo(t).Implements(jsonMarshalerType) {
-		fallback := getEncoderBase(t)
-		enc = addrJSONMarshalerEncoder{fallback: fallback}
-		EncoderCache.Store(t, enc)
-		return enc
+		return addrJSONMarshalerEncoder{fallback: c.getEncoderBase(t)}
 	}
 	if t.Implements(textMarshalerType) {
-		enc = jsonTextMarshalerEncoder{}
-		EncoderCache.Store(t, enc)
-		return enc
+		return jsonTextMarshalerEncoder{}
 	}
 	if t.Kind() != reflect.Ptr && reflect.PointerTo(t).Implements(textMarshalerType) {
-		fallback := getEncoderBase(t)
-		enc = addrJSONTextMarshalerEncoder{fallback: fallback}
-		EncoderCache.Store(t, enc)
-		return enc
+		return addrJSONTextMarshalerEncoder{fallback: c.getEncoderBase(t)}
 	}
 
-	enc = getEncoderBase(t)
-	EncoderCache.Store(t, enc)
-	return enc
+	return c.getEncoderBase(t)
 }
 
 // getEncoderBase 构建不考虑 Marshaler 接口的基础编码器（内部使用，避免递归检查接口）
-func getEncoderBase(t reflect.Type) Encoder {
+func (c *codecCache) getEncoderBase(t reflect.Type) Encoder {
 	var enc Encoder
 
 	// 使用预分配的基本类型编码器实例
@@ -235,8 +399,8 @@ func getEncoderBase(t reflect.Type) Encoder {
 	case reflect.String:
 		enc = stringEncoderInst
 	case reflect.Slice, reflect.Array:
-		if t.Elem().Kind() == reflect.Uint8 {
-			// []byte 特殊处理为字符串
+		if t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 {
+			// []byte 特殊处理为字符串（[N]byte 与 encoding/json 一致按数字数组编码）
 			enc = byteSliceEncoder{}
 		} else {
 			// 检查是否有缓存的 sliceEncoder
@@ -249,27 +413,28 @@ func getEncoderBase(t reflect.Type) Encoder {
 			}
 		}
 	case reflect.Map:
-		// 针对 map[string]interface{} 类型优化
+		// 键可以是字符串、整数，或实现了 encoding.TextMarshaler 的任意类型（与 encoding/json 一致）
+		keyOK := t.Key().Implements(textMarshalerType)
 		switch t.Key().Kind() {
 		case reflect.String,
 			reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
 			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			keyOK = true
+		}
+		switch {
+		case keyOK:
+			// 针对 map[string]interface{} 类型优化
 			if t.Elem().Kind() == reflect.Interface {
 				enc = mapStringInterfaceEncoder{
 					keyType:   t.Key(),
 					valueType: t.Elem(),
 				}
 			} else {
-				if cachedEnc, ok := mapEncoderPool.Load(t.Elem()); ok {
-					enc = cachedEnc.(Encoder)
-				} else {
-					mapEnc := mapEncoder{
-						keyType:      t.Key(),
-						valueType:    t.Elem(),
-						valueEncoder: getEncoder(t.Elem()),
-					}
-					mapEncoderPool.Store(t.Elem(), mapEnc)
-					enc = mapEnc
+				// 值编码器来自当前缓存，map 编码器本身由 getEncoder 按类型缓存
+				enc = mapEncoder{
+					keyType:      t.Key(),
+					valueType:    t.Elem(),
+					valueEncoder: c.getEncoder(t.Elem()),
 				}
 			}
 		default:
@@ -277,15 +442,13 @@ func getEncoderBase(t reflect.Type) Encoder {
 		}
 	case reflect.Struct:
 		// 结构体编码器优化，预缓存字段信息
-		fields := getStructFields(t)
+		fields := c.getStructFields(t)
 
 		// 统计字段信息用于优化
-		hasOmitEmpty := false
+		hasOmitEmpty, hasIndirect := false, false
 		for _, field := range fields {
-			if field.omitempty {
-				hasOmitEmpty = true
-				break
-			}
+			hasOmitEmpty = hasOmitEmpty || field.omitempty || field.omitzero
+			hasIndirect = hasIndirect || field.indirect
 		}
 
 		enc = &structEncoder{
@@ -293,7 +456,8 @@ func getEncoderBase(t reflect.Type) Encoder {
 			fields:       fields,
 			numFields:    len(fields),
 			hasOmitEmpty: hasOmitEmpty,
-			opcodes:      newStructOpcodeProgram(t, fields),
+			hasIndirect:  hasIndirect,
+			opcodes:      c.newStructOpcodeProgram(t, fields),
 		}
 	case reflect.Interface:
 		enc = interfaceEncoderInst
@@ -2,6 +2,7 @@ package sjson
 
 import (
 	"fmt"
+	"math"
 	"reflect"
 	"strconv"
 )
@@ -64,35 +65,91 @@ func (e float64Encoder) appendToBytes(stream *encoderStream, src reflect.Value)
 	return appendFloat64(stream, f)
 }
 
-// 优化的浮点数编码函数，参考 jsoniter 的实现
+// 浮点数编码：默认输出能精确往返（round-trip）的最短表示，格式与 encoding/json 一致
+// （绝对值位于 [1e-6, 1e21) 时用 'f'，否则用 'e' 并把 e-07 规整为 e-7）。
+// Config.FloatPrecision > 0 时退回到固定有效位数的 'g' 格式（参考 jsoniter ConfigFastest）。
 //
 //go:inline
 func appendFloat32(stream *encoderStream, f float32) error {
-
-	// 检查是否为整数浮点数
-	if f == float32(int32(f)) && f >= -2147483648 && f <= 2147483647 {
+	// 整数浮点数直接按整数输出。只限 |f| <= 2^24：更大的整数 float32 按整数写出会多出
+	// 最短往返表示之外的数字（123456789 写成 123456792 而不是 123456790）；-0 需保留符号
+	if f >= -1<<24 && f <= 1<<24 && f == float32(int32(f)) && (f != 0 || !math.Signbit(float64(f))) {
 		stream.buffer = appendInt(stream.buffer, int64(f), 10)
 		return nil
 	}
 
-	// 使用 6 位精度进行快速编码（参考 jsoniter ConfigFastest）
-	stream.buffer = strconv.AppendFloat(stream.buffer, float64(f), 'g', 6, 32)
+	if math.IsNaN(float64(f)) || math.IsInf(float64(f), 0) {
+		return appendNonFiniteFloat(stream, float64(f), 32)
+	}
+	if prec := stream.config.FloatPrecision; prec > 0 {
+		stream.buffer = strconv.AppendFloat(stream.buffer, float64(f), 'g', prec, 32)
+		return nil
+	}
+	stream.buffer = appendFloatShortest(stream.buffer, float64(f), 32)
 	return nil
 }
 
 //go:inline
 func appendFloat64(stream *encoderStream, f float64) error {
-	// 检查是否为整数浮点数
-	if f == float64(int64(f)) && f >= -9223372036854775808 && f <= 9223372036854775807 {
+	// 整数浮点数直接按整数输出。只限 |f| <= 2^53：更大的整数 float64 不一定能由最短表示的
+	// 每一位数字精确给出（1<<60 应写成 1152921504606847000）；-0 需保留符号
+	if f >= -1<<53 && f <= 1<<53 && f == float64(int64(f)) && (f != 0 || !math.Signbit(f)) {
 		stream.buffer = appendInt(stream.buffer, int64(f), 10)
 		return nil
 	}
 
-	// 使用 6 位精度进行快速编码（参考 jsoniter ConfigFastest）
-	stream.buffer = strconv.AppendFloat(stream.buffer, f, 'g', 6, 64)
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return appendNonFiniteFloat(stream, f, 64)
+	}
+	if prec := stream.config.FloatPrecision; prec > 0 {
+		stream.buffer = strconv.AppendFloat(stream.buffer, f, 'g', prec, 64)
+		return nil
+	}
+	stream.buffer = appendFloatShortest(stream.buffer, f, 64)
 	return nil
 }
 
+// appendNonFiniteFloat 按 Config.NonFiniteFloats 处理 NaN / ±Inf，默认返回错误
+func appendNonFiniteFloat(stream *encoderStream, f float64, bits int) error {
+	switch stream.config.NonFiniteFloats {
+	case NonFiniteAsNull:
+		stream.buffer = append(stream.buffer, nullString...)
+		return nil
+	case NonFiniteAsString:
+		stream.buffer = append(stream.buffer, '"')
+		stream.buffer = strconv.AppendFloat(stream.buffer, f, 'g', -1, bits)
+		stream.buffer = append(stream.buffer, '"')
+		return nil
+	}
+	value := reflect.ValueOf(f)
+	if bits == 32 {
+		value = reflect.ValueOf(float32(f))
+	}
+	return &UnsupportedValueError{Value: value, Str: strconv.FormatFloat(f, 'g', -1, bits)}
+}
+
+// appendFloatShortest 按 encoding/json 的规则输出最短往返表示
+func appendFloatShortest(b []byte, f float64, bits int) []byte {
+	abs := math.Abs(f)
+	format := byte('f')
+	if abs != 0 {
+		if bits == 64 && (abs < 1e-6 || abs >= 1e21) ||
+			bits == 32 && (float32(abs) < 1e-6 || float32(abs) >= 1e21) {
+			format = 'e'
+		}
+	}
+	b = strconv.AppendFloat(b, f, format, -1, bits)
+	if format == 'e' {
+		// 把 e-09 规整为 e-9
+		n := len(b)
+		if n >= 4 && b[n-4] == 'e' && b[n-3] == '-' && b[n-2] == '0' {
+			b[n-2] = b[n-1]
+			b = b[:n-1]
+		}
+	}
+	return b
+}
+
 type defaultEncoder struct{}
 
 func (e defaultEncoder) appendToBytes(stream *encoderStream, src reflect.Value) error {
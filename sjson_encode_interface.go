@@ -9,7 +9,9 @@ type sliceEncoder struct {
 }
 
 func (e sliceEncoder) appendToBytes(stream *encoderStream, src reflect.Value) error {
-	if src.IsNil() {
+	// sliceEncoder 同时用于数组，数组没有 nil，也不会形成环
+	isSlice := src.Kind() == reflect.Slice
+	if isSlice && src.IsNil() {
 		stream.buffer = append(stream.buffer, nullString...)
 		return nil
 	}
@@ -20,58 +22,66 @@ func (e sliceEncoder) appendToBytes(stream *encoderStream, src reflect.Value) er
 		return nil
 	}
 
-	// 快速路径：[]int
-	if e.elemType.Kind() == reflect.Int {
+	if !isSlice {
+		return e.encodeElems(stream, src, length)
+	}
+	if err := stream.enterRef(src); err != nil {
+		return err
+	}
+	err := e.encodeElems(stream, src, length)
+	stream.leaveRef(src)
+	return err
+}
+
+func (e sliceEncoder) encodeElems(stream *encoderStream, src reflect.Value, length int) error {
+	// 快速路径：[]int（精确类型匹配，具名元素类型可能有自定义编码）
+	if e.elemType == exactIntType {
 		return encodeIntSliceFast(stream, src)
 	}
 
 	// 快速路径：[]string
-	if e.elemType.Kind() == reflect.String {
+	if e.elemType == exactStringType {
 		return encodeStringSliceFast(stream, src)
 	}
 
 	// 快速路径：[]float64
-	if e.elemType.Kind() == reflect.Float64 {
+	if e.elemType == exactFloat64Type {
 		return encodeFloat64SliceFastImpl(stream, src)
 	}
 
-	stream.buffer = append(stream.buffer, '[')
+	stream.openComposite('[')
 
 	// 获取元素的编码器
-	elemEncoder := getEncoder(e.elemType)
+	elemEncoder := stream.codecs.getEncoder(e.elemType)
 
 	var err error
 
 	// 编码剩余元素
 	for i := 0; i < length; i++ {
-		if i > 0 {
-			stream.buffer = append(stream.buffer, ',')
-		}
+		stream.elemSep(i)
 		err = elemEncoder.appendToBytes(stream, src.Index(i))
 		if err != nil {
-			return err
+			return withIndexPath(err, i)
 		}
 	}
 
-	stream.buffer = append(stream.buffer, ']')
+	stream.closeComposite(']', true)
 	return nil
 }
 
 // encodeFloat64SliceFastImpl 快速编码 []float64
 func encodeFloat64SliceFastImpl(stream *encoderStream, src reflect.Value) error {
 	length := src.Len()
-	stream.buffer = append(stream.buffer, '[')
+	stream.openComposite('[')
 
 	for i := 0; i < length; i++ {
-		if i > 0 {
-			stream.buffer = append(stream.buffer, ',')
-		}
+		stream.elemSep(i)
 		if err := appendFloat64(stream, src.Index(i).Float()); err != nil {
-			return err
+			return withIndexPath(err, i)
 		}
 	}
 
-	stream.buffer = append(stream.buffer, ']')
+	stream.closeComposite(']', true)
 	return nil
 }
 
@@ -86,31 +96,34 @@ func (e interfaceEncoder) appendToBytes(stream *encoderStream, src reflect.Value
 	// 获取接口中实际的值
 	elem := src.Elem()
 
-	// 快速路径：直接处理常见类型，避免getEncoder调用
-	switch elem.Kind() {
-	case reflect.String:
-		return encodeStringDirect(stream, elem.String())
-	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		stream.buffer = appendInt(stream.buffer, elem.Int(), 10)
-		return nil
-	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-		stream.buffer = appendUint(stream.buffer, elem.Uint(), 10)
-		return nil
-	case reflect.Float64:
-		return appendFloat64(stream, elem.Float())
-	case reflect.Float32:
-		return appendFloat32(stream, float32(elem.Float()))
-	case reflect.Bool:
-		if elem.Bool() {
-			stream.buffer = append(stream.buffer, trueString...)
-		} else {
-			stream.buffer = append(stream.buffer, falseString...)
+	// 快速路径：直接处理常见类型，避免getEncoder调用。
+	// 具名类型可能实现了 Marshaler 或注册了编码函数，交给 getEncoder
+	if elem.Type().PkgPath() == "" {
+		switch elem.Kind() {
+		case reflect.String:
+			return encodeStringDirect(stream, elem.String())
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			stream.buffer = appendInt(stream.buffer, elem.Int(), 10)
+			return nil
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			stream.buffer = appendUint(stream.buffer, elem.Uint(), 10)
+			return nil
+		case reflect.Float64:
+			return appendFloat64(stream, elem.Float())
+		case reflect.Float32:
+			return appendFloat32(stream, float32(elem.Float()))
+		case reflect.Bool:
+			if elem.Bool() {
+				stream.buffer = append(stream.buffer, trueString...)
+			} else {
+				stream.buffer = append(stream.buffer, falseString...)
+			}
+			return nil
 		}
-		return nil
 	}
 
 	// 获取元素的编码器
-	elemEncoder := getEncoder(elem.Type())
+	elemEncoder := stream.codecs.getEncoder(elem.Type())
 	return elemEncoder.appendToBytes(stream, elem)
 }
 
@@ -124,10 +137,16 @@ func (e ptrEncoder) appendToBytes(stream *encoderStream, src reflect.Value) erro
 		return nil
 	}
 
+	if err := stream.enterRef(src); err != nil {
+		return err
+	}
+
 	// 获取指针指向的值
 	elemVal := src.Elem()
 
 	// 使用预先缓存的元素编码器
-	elemEncoder := getEncoder(e.elemType)
-	return elemEncoder.appendToBytes(stream, elemVal)
+	elemEncoder := stream.codecs.getEncoder(e.elemType)
+	err := elemEncoder.appendToBytes(stream, elemVal)
+	stream.leaveRef(src)
+	return err
 }
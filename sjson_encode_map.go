@@ -7,7 +7,6 @@ import (
 	"reflect"
 	"slices"
 	"sync"
-	"unicode/utf8"
 )
 
 // 对象池优化：复用 reflectWithString 切片
@@ -30,40 +29,19 @@ func putReflectWithStringSlice(slice []reflectWithString) {
 		reflectWithStringPool.Put(slice)
 	}
 }
-// encodeMapKey 将 map 键编码为 JSON 字符串（带转义）
-func encodeMapKey(stream *encoderStream, ks []byte) {
-	stream.buffer = append(stream.buffer, '"')
-	// 检查是否需要转义
-	needsEscape := false
-	for i := 0; i < len(ks); i++ {
-		if ks[i] < utf8.RuneSelf && !safeSet[ks[i]] {
-			needsEscape = true
-			break
-		}
+
+// encodeMapKey 将 map 键编码为 JSON 字符串（带转义）并写入冒号
+func encodeMapKey(stream *encoderStream, ks []byte) error {
+	if err := encodeStringDirect(stream, bytesToString(ks)); err != nil {
+		return err
 	}
-	if !needsEscape {
-		stream.buffer = append(stream.buffer, ks...)
-	} else {
-		for i := 0; i < len(ks); {
-			if c := ks[i]; c < utf8.RuneSelf {
-				if !safeSet[c] {
-					stream.buffer = escapeStringToBytes(stream.buffer, c)
-					i++
-				} else {
-					stream.buffer = append(stream.buffer, c)
-					i++
-				}
-			} else {
-				_, size := utf8.DecodeRune(ks[i:])
-				stream.buffer = append(stream.buffer, ks[i:i+size]...)
-				i += size
-			}
-		}
+	stream.buffer = append(stream.buffer, ':')
+	if stream.indenting {
+		stream.buffer = append(stream.buffer, ' ')
 	}
-	stream.buffer = append(stream.buffer, '"', ':')
+	return nil
 }
 
-
 // map[string]interface{} 专用编码器
 type mapStringInterfaceEncoder struct {
 	keyType   reflect.Type
@@ -83,18 +61,24 @@ func (e mapStringInterfaceEncoder) appendToBytes(stream *encoderStream, src refl
 		return nil
 	}
 
+	if err := stream.enterRef(src); err != nil {
+		return err
+	}
+
 	// 开始构建JSON对象
-	stream.buffer = append(stream.buffer, '{')
+	stream.openComposite('{')
 
 	var mi = src.MapRange()
 
 	// 根据map大小选择不同的编码策略
+	var err error
 	if mapLen == 1 {
-		return e.encodeSinglePair(stream, mi)
+		err = e.encodeSinglePair(stream, mi)
+	} else {
+		err = e.encodeMultiplePairs(stream, mi, mapLen)
 	}
-
-	return e.encodeMultiplePairs(stream, mi, mapLen)
-
+	stream.leaveRef(src)
+	return err
 }
 
 // 编码单个键值对（优化路径）
@@ -105,16 +89,19 @@ func (e mapStringInterfaceEncoder) encodeSinglePair(stream *encoderStream, mi *r
 		return fmt.Errorf("json: encoding error for map key: %q", err.Error())
 	}
 
-	encodeMapKey(stream, ks)
+	stream.elemSep(0)
+	if err := encodeMapKey(stream, ks); err != nil {
+		return err
+	}
 
 	miValue := mi.Value()
 
 	// 快速路径：直接处理常见interface{}内部类型
 	if err := encodeInterfaceValueFast(stream, miValue); err != nil {
-		return err
+		return withFieldPath(err, string(ks))
 	}
 
-	stream.buffer = append(stream.buffer, '}')
+	stream.closeComposite('}', true)
 	return nil
 }
 
@@ -126,56 +113,59 @@ func encodeInterfaceValueFast(stream *encoderStream, v reflect.Value) error {
 	}
 
 	elem := v.Elem()
-	switch elem.Kind() {
-	case reflect.String:
-		return encodeStringDirect(stream, elem.String())
-	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		stream.buffer = appendInt(stream.buffer, elem.Int(), 10)
-		return nil
-	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-		stream.buffer = appendUint(stream.buffer, elem.Uint(), 10)
-		return nil
-	case reflect.Float64:
-		return appendFloat64(stream, elem.Float())
-	case reflect.Float32:
-		return appendFloat32(stream, float32(elem.Float()))
-	case reflect.Bool:
-		if elem.Bool() {
-			stream.buffer = append(stream.buffer, trueString...)
-		} else {
-			stream.buffer = append(stream.buffer, falseString...)
-		}
-		return nil
-	case reflect.Slice:
-		if elem.IsNil() {
-			stream.buffer = append(stream.buffer, nullString...)
+	// 具名类型可能实现了 Marshaler 或注册了编码函数，不走按 Kind 分派的快速路径
+	if elem.Type().PkgPath() == "" {
+		switch elem.Kind() {
+		case reflect.String:
+			return encodeStringDirect(stream, elem.String())
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			stream.buffer = appendInt(stream.buffer, elem.Int(), 10)
 			return nil
-		}
-		// 检查是否是 []interface{}
-		if elem.Type().Elem().Kind() == reflect.Interface {
-			return encodeInterfaceSliceFast(stream, elem)
-		}
-		// 检查是否是 []string
-		if elem.Type().Elem().Kind() == reflect.String {
-			return encodeStringSliceFast(stream, elem)
-		}
-		// 检查是否是 []int
-		if elem.Type().Elem().Kind() == reflect.Int {
-			return encodeIntSliceFast(stream, elem)
-		}
-	case reflect.Map:
-		if elem.IsNil() {
-			stream.buffer = append(stream.buffer, nullString...)
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			stream.buffer = appendUint(stream.buffer, elem.Uint(), 10)
 			return nil
-		}
-		// 检查是否是 map[string]interface{}
-		if elem.Type().Key().Kind() == reflect.String && elem.Type().Elem().Kind() == reflect.Interface {
-			return encodeMapStringInterfaceFast(stream, elem)
+		case reflect.Float64:
+			return appendFloat64(stream, elem.Float())
+		case reflect.Float32:
+			return appendFloat32(stream, float32(elem.Float()))
+		case reflect.Bool:
+			if elem.Bool() {
+				stream.buffer = append(stream.buffer, trueString...)
+			} else {
+				stream.buffer = append(stream.buffer, falseString...)
+			}
+			return nil
+		case reflect.Slice:
+			if elem.IsNil() {
+				stream.buffer = append(stream.buffer, nullString...)
+				return nil
+			}
+			// 检查是否是 []interface{}
+			if elem.Type().Elem() == interfaceType {
+				return encodeInterfaceSliceFast(stream, elem)
+			}
+			// 检查是否是 []string
+			if elem.Type().Elem() == exactStringType {
+				return encodeStringSliceFast(stream, elem)
+			}
+			// 检查是否是 []int
+			if elem.Type().Elem() == exactIntType {
+				return encodeIntSliceFast(stream, elem)
+			}
+		case reflect.Map:
+			if elem.IsNil() {
+				stream.buffer = append(stream.buffer, nullString...)
+				return nil
+			}
+			// 检查是否是 map[string]interface{}
+			if elem.Type().Key() == exactStringType && elem.Type().Elem() == interfaceType {
+				return encodeMapStringInterfaceFast(stream, elem)
+			}
 		}
 	}
 
 	// 回退到通用编码器
-	elemEncoder := getEncoderFast(elem.Type())
+	elemEncoder := stream.codecs.getEncoderFast(elem.Type())
 	return elemEncoder.appendToBytes(stream, elem)
 }
 
@@ -187,18 +177,22 @@ func encodeInterfaceSliceFast(stream *encoderStream, src reflect.Value) error {
 		return nil
 	}
 
-	stream.buffer = append(stream.buffer, '[')
+	if err := stream.enterRef(src); err != nil {
+		return err
+	}
+
+	stream.openComposite('[')
 
 	for i := 0; i < length; i++ {
-		if i > 0 {
-			stream.buffer = append(stream.buffer, ',')
-		}
+		stream.elemSep(i)
 		if err := encodeInterfaceValueFast(stream, src.Index(i)); err != nil {
-			return err
+			stream.leaveRef(src)
+			return withIndexPath(err, i)
 		}
 	}
 
-	stream.buffer = append(stream.buffer, ']')
+	stream.closeComposite(']', true)
+	stream.leaveRef(src)
 	return nil
 }
 
@@ -210,18 +204,16 @@ func encodeStringSliceFast(stream *encoderStream, src reflect.Value) error {
 		return nil
 	}
 
-	stream.buffer = append(stream.buffer, '[')
+	stream.openComposite('[')
 
 	for i := 0; i < length; i++ {
-		if i > 0 {
-			stream.buffer = append(stream.buffer, ',')
-		}
+		stream.elemSep(i)
 		if err := encodeStringDirect(stream, src.Index(i).String()); err != nil {
 			return err
 		}
 	}
 
-	stream.buffer = append(stream.buffer, ']')
+	stream.closeComposite(']', true)
 	return nil
 }
 
@@ -233,16 +225,14 @@ func encodeIntSliceFast(stream *encoderStream, src reflect.Value) error {
 		return nil
 	}
 
-	stream.buffer = append(stream.buffer, '[')
+	stream.openComposite('[')
 
 	for i := 0; i < length; i++ {
-		if i > 0 {
-			stream.buffer = append(stream.buffer, ',')
-		}
+		stream.elemSep(i)
 		stream.buffer = appendInt(stream.buffer, src.Index(i).Int(), 10)
 	}
 
-	stream.buffer = append(stream.buffer, ']')
+	stream.closeComposite(']', true)
 	return nil
 }
 
@@ -254,32 +244,43 @@ func encodeMapStringInterfaceFast(stream *encoderStream, src reflect.Value) erro
 		return nil
 	}
 
-	stream.buffer = append(stream.buffer, '{')
+	// 需要排序时交给 mapStringInterfaceEncoder，保证嵌套在 interface{} 中的 map 同样有序
+	if stream.config.SortMapKeys {
+		return mapStringInterfaceEncoder{}.appendToBytes(stream, src)
+	}
+
+	if err := stream.enterRef(src); err != nil {
+		return err
+	}
+
+	stream.openComposite('{')
 
 	mi := src.MapRange()
-	first := true
-	for mi.Next() {
-		if !first {
-			stream.buffer = append(stream.buffer, ',')
-		}
-		first = false
+	for i := 0; mi.Next(); i++ {
+		stream.elemSep(i)
 
 		// 编码键
-		encodeMapKey(stream, stringToBytes(mi.Key().String()))
+		key := mi.Key().String()
+		if err := encodeMapKey(stream, stringToBytes(key)); err != nil {
+			stream.leaveRef(src)
+			return err
+		}
 
 		// 编码值
 		if err := encodeInterfaceValueFast(stream, mi.Value()); err != nil {
-			return err
+			stream.leaveRef(src)
+			return withFieldPath(err, key)
 		}
 	}
 
-	stream.buffer = append(stream.buffer, '}')
+	stream.closeComposite('}', true)
+	stream.leaveRef(src)
 	return nil
 }
 
 // 编码多个键值对
 func (e mapStringInterfaceEncoder) encodeMultiplePairs(stream *encoderStream, mi *reflect.MapIter, mapLen int) error {
-	if defaultConfig.SortMapKeys {
+	if stream.config.SortMapKeys {
 		return e.encodeSortedPairs(stream, mi, mapLen)
 	}
 	return e.encodeUnsortedPairs(stream, mi)
@@ -311,17 +312,17 @@ func (e mapStringInterfaceEncoder) encodeSortedPairs(stream *encoderStream, mi *
 	})
 
 	for i, kv := range sv {
-		if i > 0 {
-			stream.buffer = append(stream.buffer, ',')
+		stream.elemSep(i)
+		if err := encodeMapKey(stream, kv.ks); err != nil {
+			return err
 		}
-		encodeMapKey(stream, kv.ks)
 
 		if err := encodeInterfaceValueFast(stream, kv.v); err != nil {
-			return err
+			return withFieldPath(err, string(kv.ks))
 		}
 	}
 
-	stream.buffer = append(stream.buffer, '}')
+	stream.closeComposite('}', true)
 	return nil
 }
 
@@ -333,17 +334,17 @@ func (e mapStringInterfaceEncoder) encodeUnsortedPairs(stream *encoderStream, mi
 			return fmt.Errorf("json: encoding error for map key: %q", err.Error())
 		}
 
-		if i > 0 {
-			stream.buffer = append(stream.buffer, ',')
+		stream.elemSep(i)
+		if err := encodeMapKey(stream, ks); err != nil {
+			return err
 		}
-		encodeMapKey(stream, ks)
 
 		if err := encodeInterfaceValueFast(stream, mi.Value()); err != nil {
-			return err
+			return withFieldPath(err, string(ks))
 		}
 	}
 
-	stream.buffer = append(stream.buffer, '}')
+	stream.closeComposite('}', true)
 	return nil
 }
 
@@ -366,18 +367,24 @@ func (e mapEncoder) appendToBytes(stream *encoderStream, src reflect.Value) erro
 		return nil
 	}
 
+	if err := stream.enterRef(src); err != nil {
+		return err
+	}
+
 	// 开始构建JSON对象
-	stream.buffer = append(stream.buffer, '{')
+	stream.openComposite('{')
 
 	var mi = src.MapRange()
 
 	// 根据map大小选择不同的编码策略
+	var err error
 	if mapLen == 1 {
-		return e.encodeSinglePair(stream, mi)
+		err = e.encodeSinglePair(stream, mi)
+	} else {
+		err = e.encodeMultiplePairs(stream, mi, mapLen)
 	}
-
-	return e.encodeMultiplePairs(stream, mi, mapLen)
-
+	stream.leaveRef(src)
+	return err
 }
 
 // 编码单个键值对（优化路径）
@@ -388,20 +395,23 @@ func (e mapEncoder) encodeSinglePair(stream *encoderStream, mi *reflect.MapIter)
 		return fmt.Errorf("json: encoding error for map key: %q", err.Error())
 	}
 
-	encodeMapKey(stream, ks)
+	stream.elemSep(0)
+	if err := encodeMapKey(stream, ks); err != nil {
+		return err
+	}
 
 	err = e.valueEncoder.appendToBytes(stream, mi.Value())
 	if err != nil {
-		return err
+		return withFieldPath(err, string(ks))
 	}
 
-	stream.buffer = append(stream.buffer, '}')
+	stream.closeComposite('}', true)
 	return nil
 }
 
 // 编码多个键值对
 func (e mapEncoder) encodeMultiplePairs(stream *encoderStream, mi *reflect.MapIter, mapLen int) error {
-	if defaultConfig.SortMapKeys {
+	if stream.config.SortMapKeys {
 		return e.encodeSortedPairs(stream, mi, mapLen)
 	}
 	return e.encodeUnsortedPairs(stream, mi)
@@ -433,18 +443,18 @@ func (e mapEncoder) encodeSortedPairs(stream *encoderStream, mi *reflect.MapIter
 	})
 
 	for i, kv := range sv {
-		if i > 0 {
-			stream.buffer = append(stream.buffer, ',')
+		stream.elemSep(i)
+		if err := encodeMapKey(stream, kv.ks); err != nil {
+			return err
 		}
-		encodeMapKey(stream, kv.ks)
 
 		err := e.valueEncoder.appendToBytes(stream, kv.v)
 		if err != nil {
-			return err
+			return withFieldPath(err, string(kv.ks))
 		}
 	}
 
-	stream.buffer = append(stream.buffer, '}')
+	stream.closeComposite('}', true)
 	return nil
 }
 
@@ -456,18 +466,18 @@ func (e mapEncoder) encodeUnsortedPairs(stream *encoderStream, mi *reflect.MapIt
 			return fmt.Errorf("json: encoding error for map key: %q", err.Error())
 		}
 
-		if i > 0 {
-			stream.buffer = append(stream.buffer, ',')
+		stream.elemSep(i)
+		if err := encodeMapKey(stream, ks); err != nil {
+			return err
 		}
-		encodeMapKey(stream, ks)
 
 		err = e.valueEncoder.appendToBytes(stream, mi.Value())
 		if err != nil {
-			return err
+			return withFieldPath(err, string(ks))
 		}
 	}
 
-	stream.buffer = append(stream.buffer, '}')
+	stream.closeComposite('}', true)
 	return nil
 }
 
@@ -0,0 +1,74 @@
+package sjson
+
+import (
+	"io"
+	"reflect"
+)
+
+// streamFlushThreshold 流式编码时缓冲区达到该大小即写出到 io.Writer。
+// 取值低于 releaseEncoderStream 的回收上限，保证缓冲区可以放回对象池复用。
+const streamFlushThreshold = 32 << 10
+
+// StreamEncoder 将 JSON 值依次写入 io.Writer，对应 encoding/json.Encoder。
+// 编码过程中缓冲区每超过 streamFlushThreshold 就写出一次，
+// 因此编码大数组、大 map 时内存占用与输出大小无关。
+//
+// 与 encoding/json 不同，编码中途出错时已写出的部分不会撤回。
+type StreamEncoder struct {
+	w   io.Writer
+	err error
+	api *API
+
+	prefix, indent string
+
+	// escapeHTMLSet 为 false 时沿用 api 配置中的 EscapeHTML
+	escapeHTML    bool
+	escapeHTMLSet bool
+}
+
+// NewStreamEncoder 创建写入 w 的流式编码器，使用创建时的全局默认配置
+func NewStreamEncoder(w io.Writer) *StreamEncoder {
+	return loadDefaultAPI().NewEncoder(w)
+}
+
+// SetIndent 设置后续 Encode 的缩进，含义与 MarshalIndent 相同；两者都为空时输出紧凑格式
+func (enc *StreamEncoder) SetIndent(prefix, indent string) {
+	enc.prefix, enc.indent = prefix, indent
+}
+
+// SetEscapeHTML 指定是否将 <、>、& 转义为 \u003c、\u003e、\u0026，覆盖 Config.EscapeHTML
+func (enc *StreamEncoder) SetEscapeHTML(on bool) {
+	enc.escapeHTML, enc.escapeHTMLSet = on, true
+}
+
+// Encode 将 v 编码为 JSON 写入 w，并在末尾追加换行符。
+// 写入 w 失败后编码器不再可用，之后的调用都返回同一个错误。
+func (enc *StreamEncoder) Encode(v interface{}) error {
+	if enc.err != nil {
+		return enc.err
+	}
+
+	stream := getEncoderStream(enc.api)
+	stream.w = enc.w
+	if enc.escapeHTMLSet {
+		if enc.escapeHTML {
+			stream.escape |= escapeHTML
+		} else {
+			stream.escape &^= escapeHTML
+		}
+	}
+	if enc.prefix != "" || enc.indent != "" {
+		stream.indenting = true
+		stream.prefix, stream.indent = enc.prefix, enc.indent
+	}
+
+	err := encodeValueToBytes(stream, reflect.ValueOf(v), reflect.TypeOf(v))
+	if err == nil {
+		stream.buffer = append(stream.buffer, '\n')
+		stream.flush()
+		err = stream.werr
+		enc.err = err
+	}
+	releaseEncoderStream(stream)
+	return err
+}
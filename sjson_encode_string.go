@@ -3,6 +3,8 @@ package sjson
 import (
 	"encoding/base64"
 	"reflect"
+	"strings"
+	"unicode/utf16"
 	"unicode/utf8"
 	"unsafe"
 )
@@ -76,6 +78,21 @@ func escapeStringToBytes(buf []byte, c byte) []byte {
 	return buf
 }
 
+// escapeFlags 是 Config 中字符串转义相关选项的位标记，见 Config.escapeFlags
+type escapeFlags uint8
+
+const (
+	escapeHTML escapeFlags = 1 << iota
+	escapeLineTerminators
+	escapeASCIIOnly
+	escapeInvalidUTF8Replace
+	escapeInvalidUTF8Reject
+
+	escapeFlagSets = int(escapeInvalidUTF8Reject) << 1 // 所有标记组合的个数
+)
+
+const lowerHex = "0123456789abcdef"
+
 type stringEncoder struct{}
 
 // 为stringEncoder添加appendToBytes方法
@@ -130,6 +147,47 @@ func stringNeedsEscapeSWAR(s string) bool {
 	return false
 }
 
+// stringNeedsEscapeFlags 是开启了转义选项时的快速检测：ASCII 部分仍按 8 字节批量判断，
+// 只有出现非 ASCII 字节时才按需做 U+2028/U+2029 查找或 UTF-8 合法性检查
+func stringNeedsEscapeFlags(s string, flags escapeFlags) bool {
+	html := flags&escapeHTML != 0
+	orig := s
+	var high uint64
+
+	for len(s) >= 8 {
+		chunk := stringChunk64(s)
+		if hasBytes8(chunk, 0x2222222222222222) || hasBytes8(chunk, 0x5C5C5C5C5C5C5C5C) || hasControlChars8(chunk) {
+			return true
+		}
+		if html && (hasBytes8(chunk, 0x3C3C3C3C3C3C3C3C) || hasBytes8(chunk, 0x3E3E3E3E3E3E3E3E) || hasBytes8(chunk, 0x2626262626262626)) {
+			return true
+		}
+		high |= chunk
+		s = s[8:]
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c < utf8.RuneSelf && (!safeSet[c] || html && (c == '<' || c == '>' || c == '&')) {
+			return true
+		}
+		high |= uint64(c)
+	}
+
+	if high&0x8080808080808080 == 0 {
+		return false
+	}
+	if flags&escapeASCIIOnly != 0 {
+		return true
+	}
+	if flags&escapeLineTerminators != 0 && (strings.Contains(orig, "\u2028") || strings.Contains(orig, "\u2029")) {
+		return true
+	}
+	if flags&(escapeInvalidUTF8Replace|escapeInvalidUTF8Reject) != 0 && !utf8.ValidString(orig) {
+		return true
+	}
+	return false
+}
+
 // stringChunk64 读取字符串前 8 字节为 uint64
 //
 //go:inline
@@ -147,7 +205,12 @@ func encodeStringDirect(stream *encoderStream, s string) error {
 	}
 
 	// OPT-3: SWAR 快速路径——一次检测 8 字节判断是否需要转义
-	needsEscape := stringNeedsEscapeSWAR(s)
+	var needsEscape bool
+	if stream.escape == 0 {
+		needsEscape = stringNeedsEscapeSWAR(s)
+	} else {
+		needsEscape = stringNeedsEscapeFlags(s, stream.escape)
+	}
 
 	if !needsEscape {
 		// 无需转义，直接添加
@@ -157,6 +220,10 @@ func encodeStringDirect(stream *encoderStream, s string) error {
 		return nil
 	}
 
+	if stream.escape != 0 {
+		return encodeStringEscapeFlags(stream, s)
+	}
+
 	// 需要转义，预分配足够的空间
 	needed := len(s) + 2
 	if cap(stream.buffer)-len(stream.buffer) < needed*2 {
@@ -203,6 +270,72 @@ func encodeStringDirect(stream *encoderStream, s string) error {
 	return nil
 }
 
+// encodeStringEscapeFlags 是开启转义选项时的慢路径。出错时不修改 stream.buffer。
+func encodeStringEscapeFlags(stream *encoderStream, s string) error {
+	flags := stream.escape
+	html := flags&escapeHTML != 0
+	buf := append(stream.buffer, '"')
+
+	start := 0
+	for i := 0; i < len(s); {
+		if c := s[i]; c < utf8.RuneSelf {
+			if safeSet[c] && !(html && (c == '<' || c == '>' || c == '&')) {
+				i++
+				continue
+			}
+			buf = append(buf, s[start:i]...)
+			if c == '<' || c == '>' || c == '&' {
+				buf = append(buf, '\\', 'u', '0', '0', lowerHex[c>>4], lowerHex[c&0xF])
+			} else {
+				buf = escapeStringToBytes(buf, c)
+			}
+			i++
+			start = i
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			if flags&escapeInvalidUTF8Reject != 0 {
+				return &InvalidUTF8Error{S: s}
+			}
+			if flags&(escapeInvalidUTF8Replace|escapeASCIIOnly) != 0 {
+				buf = append(buf, s[start:i]...)
+				buf = append(buf, `\ufffd`...)
+				i++
+				start = i
+				continue
+			}
+			// 原样输出
+			i++
+			continue
+		}
+
+		if flags&escapeASCIIOnly != 0 || (flags&escapeLineTerminators != 0 && (r == '\u2028' || r == '\u2029')) {
+			buf = append(buf, s[start:i]...)
+			buf = appendUnicodeEscape(buf, r)
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+
+	buf = append(buf, s[start:]...)
+	stream.buffer = append(buf, '"')
+	return nil
+}
+
+// appendUnicodeEscape 写入 \uXXXX，增补平面字符写成 UTF-16 代理对
+func appendUnicodeEscape(buf []byte, r rune) []byte {
+	if r > 0xFFFF {
+		r1, r2 := utf16.EncodeRune(r)
+		buf = appendUnicodeEscape(buf, r1)
+		return appendUnicodeEscape(buf, r2)
+	}
+	return append(buf, '\\', 'u', lowerHex[r>>12&0xF], lowerHex[r>>8&0xF], lowerHex[r>>4&0xF], lowerHex[r&0xF])
+}
+
 // []byte 专用编码器（base64 编码，与 encoding/json 一致）
 type byteSliceEncoder struct{}
 
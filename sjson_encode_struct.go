@@ -1,6 +1,7 @@
 package sjson
 
 import (
+	"math"
 	"reflect"
 	"unsafe"
 )
@@ -10,16 +11,54 @@ import (
 // offset 为预计算的 unsafe 偏移量（OPT-1），用于绕过 reflect.Value.Field() 开销
 type structField struct {
 	name      []byte
-	keyBytes  []byte // 预计算的键字节："name":"
+	keyBytes  []byte                  // 预计算的键字节："name":
+	escKeys   *[escapeFlagSets][]byte // 名字含 <、>、&、非 ASCII 等字符时，各转义选项组合下的键字节；否则为 nil
 	index     []int
 	offset    uintptr // 预计算的 unsafe 偏移量（支持嵌套匿名字段累加）
 	nameLen   int     // 字段名长度，配合 nameHead 做 (len, head) 快速等值比较
 	nameHead  uint64  // 字段名前 8 字节的小端序 uint64（不足 8 字节补零）
 	omitempty bool
+	omitzero  bool                     // json:",omitzero"
+	isZero    func(reflect.Value) bool // omitzero 字段类型的 IsZero 方法；为 nil 时按 reflect 零值判断
+	asString  bool                     // json:",string"：标量值包在字符串中编解码
+	indirect  bool                     // 经由嵌入的 *T 提升而来，路径上可能有 nil 指针，offset 无效
 	typ       reflect.Type
 	encoder   Encoder // 预缓存字段编码器
 }
 
+// key 返回按 flags 转义的键字节（与 encoding/json 的 nameEscHTML 相同，转义选项同样作用于键）
+//
+//go:inline
+func (f *structField) key(flags escapeFlags) []byte {
+	if f.escKeys == nil {
+		return f.keyBytes
+	}
+	return f.escKeys[flags]
+}
+
+// fieldKeys 预计算字段名的键字节。名字只含无需按选项转义的字符时（绝大多数字段）escKeys 为 nil
+func fieldKeys(name string) (keyBytes []byte, escKeys *[escapeFlagSets][]byte) {
+	keyBytes = appendFieldKey(nil, name, 0)
+	if !stringNeedsEscapeFlags(name, escapeHTML|escapeLineTerminators|escapeASCIIOnly|escapeInvalidUTF8Replace) {
+		return keyBytes, nil
+	}
+	escKeys = new([escapeFlagSets][]byte)
+	for flags := range escKeys {
+		escKeys[flags] = appendFieldKey(nil, name, escapeFlags(flags))
+	}
+	return keyBytes, escKeys
+}
+
+// appendFieldKey 写入 `"name":`。字段名在编译期确定，InvalidUTF8Reject 下的非法字节按替换处理而不报错
+func appendFieldKey(dst []byte, name string, flags escapeFlags) []byte {
+	if flags&escapeInvalidUTF8Reject != 0 {
+		flags = flags&^escapeInvalidUTF8Reject | escapeInvalidUTF8Replace
+	}
+	stream := encoderStream{buffer: dst, escape: flags}
+	encodeStringDirect(&stream, name)
+	return append(stream.buffer, ':')
+}
+
 // fieldByIndex 根据索引路径获取字段值，支持匿名字段的多级路径
 //
 // 注：曾用 unsafe.Pointer(base+offset) + reflect.NewAt 合成 reflect.Value（OPT-1），
@@ -34,12 +73,45 @@ func fieldByIndex(v reflect.Value, index []int) reflect.Value {
 	return v.FieldByIndex(index)
 }
 
+// omitted 报告字段值是否因 omitempty 或 omitzero 而省略
+func (f *structField) omitted(v reflect.Value) bool {
+	if f.omitempty && isEmptyValue(v) {
+		return true
+	}
+	if f.omitzero {
+		if f.isZero != nil {
+			return f.isZero(v)
+		}
+		return v.IsZero()
+	}
+	return false
+}
+
+// structFieldValue 取编码用的字段值。经由嵌入指针提升的字段逐级解引用，
+// 路径上遇到 nil 指针时返回 false，该字段不输出（与 encoding/json 一致）
+func structFieldValue(v reflect.Value, field *structField) (reflect.Value, bool) {
+	if !field.indirect {
+		return fieldByIndex(v, field.index), true
+	}
+	for i, x := range field.index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
 type structEncoder struct {
 	typ          reflect.Type
 	fields       []structField
 	numFields    int                  // 字段数量，用于优化分发
-	hasOmitEmpty bool                 // 是否有omitempty字段
-	opcodes      *structOpcodeProgram // OPT-8: 标量结构体的预编译执行程序
+	hasOmitEmpty bool                 // 是否有 omitempty / omitzero 字段
+	hasIndirect  bool                 // 是否有经由嵌入指针提升的字段
+	opcodes      *structOpcodeProgram // OPT-8: 预编译的 opcode 执行程序
 }
 
 // 添加appendToBytes方法，将结构体直接编码到字节切片
@@ -53,31 +125,32 @@ func (e *structEncoder) appendToBytes(stream *encoderStream, src reflect.Value)
 	}
 
 	// 开始对象
-	stream.buffer = append(stream.buffer, '{')
+	stream.openComposite('{')
 
-	// OPT-8/OPT-7: ShapeSig 匹配的无 omitempty 标量结构体走 opcode 快速路径。
-	// 不可寻址值和复杂字段继续使用下方通用路径，保证语义一致。
-	if e.opcodes != nil && e.opcodes.valid && !e.hasOmitEmpty && src.CanAddr() {
-		return e.opcodes.appendToBytes(stream, unsafe.Pointer(src.UnsafeAddr()), e.fields)
+	// OPT-8/OPT-7: 可寻址的结构体走 opcode 路径，按偏移量直接读取字段。
+	// 不可寻址值（如按值传给 Marshal 的结构体）继续使用下方通用路径。
+	if e.opcodes != nil && src.CanAddr() {
+		return e.opcodes.appendToBytes(stream, unsafe.Pointer(src.UnsafeAddr()), e.fields, src)
 	}
 
 	// 根据字段数量选择不同的编码策略
 	switch e.numFields {
 	case 0:
 		// 空结构体，直接返回
-		stream.buffer = append(stream.buffer, '}')
+		stream.closeComposite('}', false)
 		return nil
 	case 1:
 		// 单字段优化：直接处理，无需循环
-		return e.encodeSingleField(stream, src)
+		if !e.hasIndirect {
+			return e.encodeSingleField(stream, src)
+		}
 	default:
-		// 多字段：根据是否有omitempty选择策略
-		if e.hasOmitEmpty {
-			return e.encodeFieldsWithOmitEmpty(stream, src)
-		} else {
+		if !e.hasOmitEmpty && !e.hasIndirect {
 			return e.encodeFieldsFast(stream, src)
 		}
 	}
+	// 有 omitempty / omitzero 或经由嵌入指针提升的字段：逐个判断字段是否输出
+	return e.encodeFieldsWithOmitEmpty(stream, src)
 }
 
 // 单字段编码优化
@@ -85,22 +158,23 @@ func (e *structEncoder) encodeSingleField(stream *encoderStream, src reflect.Val
 	field := e.fields[0]
 	f := fieldByIndex(src, field.index)
 
-	// 处理omitempty
-	if field.omitempty && isEmptyValue(f) {
-		stream.buffer = append(stream.buffer, '}')
+	// 处理 omitempty / omitzero
+	if field.omitted(f) {
+		stream.closeComposite('}', false)
 		return nil
 	}
 
 	// 写入字段名
-	stream.buffer = append(stream.buffer, field.keyBytes...)
+	stream.elemSep(0)
+	stream.writeKey(field.key(stream.escape))
 
 	// 编码字段值
 	err := field.encoder.appendToBytes(stream, f)
 	if err != nil {
-		return err
+		return withFieldPath(err, bytesToString(field.name))
 	}
 
-	stream.buffer = append(stream.buffer, '}')
+	stream.closeComposite('}', true)
 	return nil
 }
 
@@ -108,53 +182,104 @@ func (e *structEncoder) encodeSingleField(stream *encoderStream, src reflect.Val
 func (e *structEncoder) encodeFieldsFast(stream *encoderStream, src reflect.Value) error {
 	for i, field := range e.fields {
 		// 添加逗号分隔符
-		if i > 0 {
-			stream.buffer = append(stream.buffer, ',')
-		}
+		stream.elemSep(i)
 
 		// 写入字段名
-		stream.buffer = append(stream.buffer, field.keyBytes...)
+		stream.writeKey(field.key(stream.escape))
 
 		// 编码字段值
 		f := fieldByIndex(src, field.index)
 		err := field.encoder.appendToBytes(stream, f)
 		if err != nil {
-			return err
+			return withFieldPath(err, bytesToString(field.name))
 		}
 	}
 
-	stream.buffer = append(stream.buffer, '}')
+	stream.closeComposite('}', true)
 	return nil
 }
 
 // 带omitempty的编码
 func (e *structEncoder) encodeFieldsWithOmitEmpty(stream *encoderStream, src reflect.Value) error {
-	firstField := true
+	written := 0
 
-	for _, field := range e.fields {
-		f := fieldByIndex(src, field.index)
+	for i := range e.fields {
+		field := &e.fields[i]
+		f, ok := structFieldValue(src, field)
 
-		// 处理omitempty标签
-		if field.omitempty && isEmptyValue(f) {
+		// 处理 nil 嵌入指针与 omitempty / omitzero 标签
+		if !ok || field.omitted(f) {
 			continue
 		}
 
 		// 添加逗号分隔符
-		if !firstField {
-			stream.buffer = append(stream.buffer, ',')
-		}
-		firstField = false
+		stream.elemSep(written)
+		written++
 
 		// 写入字段名
-		stream.buffer = append(stream.buffer, field.keyBytes...)
+		stream.writeKey(field.key(stream.escape))
 
 		// 编码字段值
 		err := field.encoder.appendToBytes(stream, f)
 		if err != nil {
+			return withFieldPath(err, bytesToString(field.name))
+		}
+	}
+
+	stream.closeComposite('}', written > 0)
+	return nil
+}
+
+// quotedEncoder 实现 `json:",string"`：把标量字段的 JSON 表示再包一层字符串，
+// 如 int64 编码为 "12345"，string 编码为 "\"abc\""
+type quotedEncoder struct {
+	elemEncoder Encoder
+}
+
+// newQuotedEncoder 为 ",string" 字段包装编码器；实现了 Marshaler 或注册了编码函数的类型按自身方式编码，不加引号
+func (c *codecCache) newQuotedEncoder(t reflect.Type, enc Encoder) Encoder {
+	base := t
+	if base.Kind() == reflect.Ptr {
+		base = base.Elem()
+	}
+	for _, typ := range []reflect.Type{base, reflect.PointerTo(base)} {
+		if typ.Implements(jsonMarshalerType) || typ.Implements(textMarshalerType) || c.typeEncoder(typ) != nil {
+			return enc
+		}
+	}
+	return quotedEncoder{elemEncoder: c.getEncoder(base)}
+}
+
+func (e quotedEncoder) appendToBytes(stream *encoderStream, src reflect.Value) error {
+	if src.Kind() == reflect.Ptr {
+		if src.IsNil() {
+			stream.buffer = append(stream.buffer, nullString...)
+			return nil
+		}
+		src = src.Elem()
+	}
+
+	switch src.Kind() {
+	case reflect.String:
+		// 先按普通字符串编码，再把结果整体作为字符串编码一次
+		start := len(stream.buffer)
+		if err := e.elemEncoder.appendToBytes(stream, src); err != nil {
 			return err
 		}
+		inner := string(stream.buffer[start:])
+		stream.buffer = stream.buffer[:start]
+		return encodeStringDirect(stream, inner)
+	case reflect.Float32, reflect.Float64:
+		// NaN/±Inf 交给 NonFiniteFloats 处理，不额外加引号
+		if f := src.Float(); math.IsNaN(f) || math.IsInf(f, 0) {
+			return e.elemEncoder.appendToBytes(stream, src)
+		}
 	}
 
-	stream.buffer = append(stream.buffer, '}')
+	stream.buffer = append(stream.buffer, '"')
+	if err := e.elemEncoder.appendToBytes(stream, src); err != nil {
+		return err
+	}
+	stream.buffer = append(stream.buffer, '"')
 	return nil
 }
@@ -1,11 +1,19 @@
 package sjson
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
+	"math/big"
 	"reflect"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 // 测试用复杂结构体
@@ -252,15 +260,8 @@ func TestMarshalSortedKeys(t *testing.T) {
 		"m": 13,
 	}
 
-	// 设置排序
-	origSortSetting := defaultConfig.SortMapKeys
-	defaultConfig.SortMapKeys = true
-	defer func() {
-		defaultConfig.SortMapKeys = origSortSetting
-	}()
-
 	// 序列化
-	result, err := MarshalString(testMap)
+	result, err := MarshalStringWithConfig(testMap, Config{SortMapKeys: true})
 	if err != nil {
 		t.Errorf("MarshalString(sortedMap) 失败: %v", err)
 	}
@@ -270,6 +271,55 @@ func TestMarshalSortedKeys(t *testing.T) {
 	if result != expected {
 		t.Errorf("MarshalString未正确排序键: 得到 %s, 期望 %s", result, expected)
 	}
+
+	// 嵌套在 interface{} 中的 map 同样排序
+	nested := map[string]interface{}{
+		"b": []interface{}{map[string]interface{}{"y": 1, "x": 2, "w": 3}},
+		"a": map[string]interface{}{"d": true, "c": nil},
+	}
+	got, err := MarshalWithConfig(nested, Config{SortMapKeys: true})
+	if err != nil {
+		t.Fatalf("MarshalWithConfig 失败: %v", err)
+	}
+	if expected := `{"a":{"c":null,"d":true},"b":[{"w":3,"x":2,"y":1}]}`; string(got) != expected {
+		t.Errorf("嵌套 map 未排序: 得到 %s, 期望 %s", got, expected)
+	}
+}
+
+// 测试不同配置的编码可以并发进行，互不影响
+func TestMarshalWithConfigConcurrent(t *testing.T) {
+	orig := GetDefaultConfig()
+	defer SetDefaultConfig(orig)
+
+	value := map[string]interface{}{"z": 0.1, "a": "<b>", "m": []interface{}{1.5}}
+	configs := []struct {
+		config   Config
+		expected string
+	}{
+		{Config{SortMapKeys: true}, `{"a":"<b>","m":[1.5],"z":0.1}`},
+		{Config{SortMapKeys: true, EscapeHTML: true, FloatPrecision: 1}, `{"a":"\u003cb\u003e","m":[2],"z":0.1}`},
+	}
+
+	var wg sync.WaitGroup
+	for _, tc := range configs {
+		tc := tc
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				got, err := AppendMarshalWithConfig(nil, value, tc.config)
+				if err != nil || string(got) != tc.expected {
+					t.Errorf("AppendMarshalWithConfig = %s, %v, 期望 %s", got, err, tc.expected)
+					return
+				}
+			}
+		}()
+	}
+	// 同时修改全局配置不影响指定了配置的调用
+	for i := 0; i < 200; i++ {
+		SetDefaultConfig(Config{SortMapKeys: i%2 == 0})
+	}
+	wg.Wait()
 }
 
 // TestMarshalInterfaceTypes 测试各种 interface{} 类型的编码
@@ -376,6 +426,1405 @@ func TestMarshalInterfaceTypes(t *testing.T) {
 	}
 }
 
+// 测试浮点数默认输出最短往返表示，且与 encoding/json 逐字节一致
+func TestMarshalFloatShortest(t *testing.T) {
+	type prices struct {
+		Price float64 `json:"price"`
+		Ratio float32 `json:"ratio"`
+	}
+
+	values := []interface{}{
+		3.14159265,
+		-0.1,
+		1e-7,
+		1e-6,
+		123456789.123,
+		1e20,
+		1e21,
+		1.5e300,
+		5e-324,
+		math.Copysign(0, -1),
+		float32(3.14159265),
+		float32(1e-7),
+		float32(16777216),
+		float32(16777217),
+		float32(123456789),
+		float32(1 << 30),
+		float32(-1 << 30),
+		float64(1 << 53),
+		float64(1<<53 + 2),
+		float64(1 << 60),
+		-float64(1 << 60),
+		9007199254740993.0,
+		1e19,
+		[]float64{0.1, 0.2, 0.30000000000000004},
+		[]interface{}{2.718281828459045, float32(0.1)},
+		// map 只放一个键：默认不排序时多个键的顺序不确定
+		map[string]float64{"lat": 39.9042151},
+		map[string]float64{"lng": 116.4073963},
+		prices{Price: 19.990000001, Ratio: 0.333333},
+		&prices{Price: 1e-9, Ratio: 1e22},
+	}
+
+	for _, v := range values {
+		want, err := json.Marshal(v)
+		if err != nil {
+			t.Fatalf("json.Marshal(%v) 失败: %v", v, err)
+		}
+		got, err := Marshal(v)
+		if err != nil {
+			t.Errorf("Marshal(%v) 失败: %v", v, err)
+			continue
+		}
+		if string(got) != string(want) {
+			t.Errorf("Marshal(%v) = %s, 期望 %s", v, got, want)
+		}
+	}
+}
+
+// 测试 FloatPrecision 配置的固定精度模式
+func TestMarshalFloatPrecisionConfig(t *testing.T) {
+	orig := GetDefaultConfig()
+	SetDefaultConfig(Config{FloatPrecision: 6})
+	defer SetDefaultConfig(orig)
+
+	type point struct {
+		X float64 `json:"x"`
+		Y float32 `json:"y"`
+	}
+
+	tests := []struct {
+		input    interface{}
+		expected string
+	}{
+		{3.14159265, `3.14159`},
+		{float32(2.7182817), `2.71828`},
+		{42.0, `42`},
+		{point{X: 1.23456789, Y: 9.87654321}, `{"x":1.23457,"y":9.87654}`},
+	}
+
+	for _, test := range tests {
+		result, err := MarshalString(test.input)
+		if err != nil {
+			t.Errorf("MarshalString(%v) 失败: %v", test.input, err)
+			continue
+		}
+		if result != test.expected {
+			t.Errorf("MarshalString(%v) = %s, 期望 %s", test.input, result, test.expected)
+		}
+	}
+}
+
+// 测试 NaN / ±Inf 默认返回带路径的 UnsupportedValueError
+func TestMarshalNonFiniteFloat(t *testing.T) {
+	type item struct {
+		Price float64 `json:"price"`
+	}
+	type order struct {
+		ID    int                `json:"id"`
+		Items []item             `json:"items"`
+		Extra map[string]float32 `json:"extra"`
+	}
+
+	tests := []struct {
+		name  string
+		input interface{}
+		str   string
+		path  string
+	}{
+		{"top-level", math.NaN(), "NaN", ""},
+		{"float32", float32(math.Inf(-1)), "-Inf", ""},
+		{"struct-field", item{Price: math.Inf(1)}, "+Inf", "price"},
+		{"nested-slice", &order{Items: []item{{1}, {math.NaN()}}}, "NaN", "items[1].price"},
+		{"map-value", order{Extra: map[string]float32{"rate": float32(math.Inf(1))}}, "+Inf", "extra.rate"},
+		{"interface", []interface{}{1, map[string]interface{}{"v": math.NaN()}}, "NaN", "[1].v"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			for _, marshal := range []func(interface{}) error{
+				func(v interface{}) error { _, err := Marshal(v); return err },
+				func(v interface{}) error { _, err := MarshalString(v); return err },
+				func(v interface{}) error { _, err := AppendMarshal(nil, v); return err },
+			} {
+				err := marshal(tc.input)
+				var uve *UnsupportedValueError
+				if !errors.As(err, &uve) {
+					t.Fatalf("期望 *UnsupportedValueError, 得到 %v", err)
+				}
+				if uve.Str != tc.str || uve.Path != tc.path {
+					t.Errorf("错误内容不符: Str=%q Path=%q, 期望 Str=%q Path=%q", uve.Str, uve.Path, tc.str, tc.path)
+				}
+				if !uve.Value.IsValid() {
+					t.Errorf("错误未携带原始值")
+				}
+			}
+		})
+	}
+}
+
+// 测试 NonFiniteFloats 的宽松输出模式
+func TestMarshalNonFiniteFloatModes(t *testing.T) {
+	orig := GetDefaultConfig()
+	defer SetDefaultConfig(orig)
+
+	input := []float64{math.NaN(), math.Inf(1), math.Inf(-1), 1.5}
+
+	SetDefaultConfig(Config{NonFiniteFloats: NonFiniteAsNull})
+	if got, err := MarshalString(input); err != nil || got != `[null,null,null,1.5]` {
+		t.Errorf("NonFiniteAsNull: got %s, err %v", got, err)
+	}
+
+	SetDefaultConfig(Config{NonFiniteFloats: NonFiniteAsString})
+	if got, err := MarshalString(input); err != nil || got != `["NaN","+Inf","-Inf",1.5]` {
+		t.Errorf("NonFiniteAsString: got %s, err %v", got, err)
+	}
+}
+
+// 测试自引用的指针、map 和切片返回 UnsupportedValueError 而不是栈溢出
+func TestMarshalCycle(t *testing.T) {
+	type node struct {
+		Val  int   `json:"val"`
+		Next *node `json:"next"`
+	}
+	list := &node{Val: 1}
+	list.Next = &node{Val: 2, Next: list}
+
+	m := map[string]interface{}{"a": 1}
+	m["self"] = m
+
+	s := []interface{}{1, nil}
+	s[1] = s
+
+	type holder struct {
+		M map[string]interface{} `json:"m"`
+	}
+
+	type tree struct {
+		Kids []tree `json:"kids"`
+	}
+	kids := make([]tree, 1)
+	kids[0].Kids = kids
+
+	tests := []struct {
+		name  string
+		input interface{}
+		typ   string
+	}{
+		{"pointer", list, "*sjson.node"},
+		{"map", m, "map[string]interface {}"},
+		{"slice", s, "[]interface {}"},
+		{"struct-field", holder{M: m}, "map[string]interface {}"},
+		{"struct-slice", &tree{Kids: kids}, "[]sjson.tree"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Marshal(tc.input)
+			var uve *UnsupportedValueError
+			if !errors.As(err, &uve) {
+				t.Fatalf("期望 *UnsupportedValueError, 得到 %v", err)
+			}
+			if want := "encountered a cycle via " + tc.typ; uve.Str != want {
+				t.Errorf("Str = %q, 期望 %q", uve.Str, want)
+			}
+			// 环在上千层之后才被发现，错误不应携带逐层累积的路径
+			if uve.Path != "" {
+				t.Errorf("Path 长度 = %d, 期望为空", len(uve.Path))
+			}
+		})
+	}
+
+	// 编码失败后流被放回对象池，不应影响后续编码
+	if got, err := MarshalString(map[string]int{"a": 1}); err != nil || got != `{"a":1}` {
+		t.Errorf("环检测失败后编码异常: %s, %v", got, err)
+	}
+}
+
+// 测试深层但无环的值与数组仍可正常编码
+func TestMarshalDeepAcyclic(t *testing.T) {
+	type node struct {
+		Next *node `json:"next,omitempty"`
+	}
+	var head *node
+	for i := 0; i < 2*startDetectingCyclesAfter; i++ {
+		head = &node{Next: head}
+	}
+	got, err := Marshal(head)
+	if err != nil {
+		t.Fatalf("Marshal 失败: %v", err)
+	}
+	expected, _ := json.Marshal(head)
+	if string(got) != string(expected) {
+		t.Errorf("深层链表编码结果与标准库不一致")
+	}
+
+	// 同一个共享子值出现多次不是环
+	shared := &node{}
+	pair := []*node{shared, shared}
+	if got, err := MarshalString(pair); err != nil || got != `[{},{}]` {
+		t.Errorf("共享指针: got %s, err %v", got, err)
+	}
+
+	arr := [3]int{1, 2, 3}
+	if got, err := MarshalString(arr); err != nil || got != `[1,2,3]` {
+		t.Errorf("数组: got %s, err %v", got, err)
+	}
+}
+
+// 递归类型：map / 数组 / 匿名嵌入的值重新引用外层类型
+type recursiveNode struct {
+	Name     string                   `json:"name"`
+	Children map[string]recursiveNode `json:"children,omitempty"`
+	Pair     [1][]recursiveNode       `json:"pair"`
+	recursiveEmbed
+}
+
+type recursiveEmbed struct {
+	Extra map[string]recursiveNode `json:"extra,omitempty"`
+}
+
+// 测试递归类型的编码器只构建一次并正确编码
+func TestMarshalRecursiveType(t *testing.T) {
+	input := recursiveNode{
+		Name: "root",
+		Children: map[string]recursiveNode{
+			"a": {Name: "a", Children: map[string]recursiveNode{"b": {Name: "b"}}},
+		},
+		Pair:           [1][]recursiveNode{{{Name: "p"}}},
+		recursiveEmbed: recursiveEmbed{Extra: map[string]recursiveNode{"x": {Name: "x"}}},
+	}
+
+	got, err := Marshal(input)
+	if err != nil {
+		t.Fatalf("Marshal 失败: %v", err)
+	}
+	expected, _ := json.Marshal(input)
+	if string(got) != string(expected) {
+		t.Errorf("Marshal = %s, 期望 %s", got, expected)
+	}
+
+	var decoded recursiveNode
+	if err := Unmarshal(got, &decoded); err != nil {
+		t.Fatalf("Unmarshal 失败: %v", err)
+	}
+	again, _ := Marshal(decoded)
+	if string(again) != string(expected) {
+		t.Errorf("往返编码 = %s, 期望 %s", again, expected)
+	}
+
+	// 缓存中不应残留构建期间的占位编码器
+	if enc, ok := EncoderCache.Load(reflect.TypeOf(input)); !ok {
+		t.Errorf("编码器未缓存")
+	} else if _, isIndirect := enc.(*indirectEncoder); isIndirect {
+		t.Errorf("缓存中残留占位编码器")
+	}
+}
+
+// 直接引用自身的具名切片类型：不经过结构体，编译指令时需要识别递归
+type (
+	recursiveTree []recursiveTree
+	recursivePtrs []*recursivePtrs
+)
+
+// 测试字段为递归切片类型时编码与 encoding/json 一致
+func TestMarshalRecursiveSliceType(t *testing.T) {
+	leaf := recursivePtrs{}
+	inputs := []interface{}{
+		&struct{ T recursiveTree }{recursiveTree{recursiveTree{}}},
+		struct {
+			T recursiveTree `json:"t,omitempty"`
+			A recursivePtrs `json:"a"`
+		}{T: recursiveTree{nil, {{}}}, A: recursivePtrs{&leaf, nil}},
+		recursiveTree{{{}, nil}},
+	}
+	for _, in := range inputs {
+		got, err := Marshal(in)
+		if err != nil {
+			t.Errorf("Marshal(%#v) 失败: %v", in, err)
+			continue
+		}
+		expected, _ := json.Marshal(in)
+		if string(got) != string(expected) {
+			t.Errorf("Marshal = %s, 期望 %s", got, expected)
+		}
+	}
+
+	// 真正的引用环仍然报错
+	cyclic := recursivePtrs{nil}
+	cyclic[0] = &cyclic
+	if _, err := Marshal(struct{ A recursivePtrs }{cyclic}); err == nil {
+		t.Error("引用环应返回错误")
+	}
+}
+
+// 测试构建编码器时 panic 不会在缓存中留下占位编码器，之后的编码重新构建
+func TestMarshalBuildEncoderPanic(t *testing.T) {
+	var calls atomic.Int32
+	namer := NewFieldNamer(func(name string) string {
+		if calls.Add(1) == 1 {
+			panic("namer failed")
+		}
+		return strings.ToLower(name)
+	})
+	api := Config{FieldNamer: namer}.Freeze()
+	type item struct{ Name string }
+
+	func() {
+		defer func() {
+			if r := recover(); r != "namer failed" {
+				t.Fatalf("recover() = %v, 期望 FieldNamer 的 panic", r)
+			}
+		}()
+		api.Marshal(item{Name: "a"})
+	}()
+
+	got, err := api.MarshalString(item{Name: "a"})
+	if err != nil || got != `{"name":"a"}` {
+		t.Errorf("panic 后再次编码 = %s, %v, 期望 {\"name\":\"a\"}", got, err)
+	}
+}
+
+// 测试 [N]byte 与 encoding/json 一致编码为数字数组
+func TestMarshalByteArray(t *testing.T) {
+	input := struct {
+		A [3]byte `json:"a"`
+		B []byte  `json:"b"`
+	}{A: [3]byte{1, 2, 3}, B: []byte{1, 2, 3}}
+	got, err := Marshal(input)
+	if err != nil {
+		t.Fatalf("Marshal 失败: %v", err)
+	}
+	expected, _ := json.Marshal(input)
+	if string(got) != string(expected) {
+		t.Errorf("Marshal = %s, 期望 %s", got, expected)
+	}
+}
+
+// rawObjectMarshaler 的 MarshalJSON 输出带任意空白，缩进模式下需要重新排版
+type rawObjectMarshaler struct{}
+
+func (rawObjectMarshaler) MarshalJSON() ([]byte, error) {
+	return []byte(`{ "k" : [1, {"x":true}] , "e":{ } }`), nil
+}
+
+// paddedMarshaler 的 MarshalJSON 输出首尾带空白，嵌入文档时应当丢弃
+type paddedMarshaler struct{}
+
+func (paddedMarshaler) MarshalJSON() ([]byte, error) {
+	return []byte(" \n[1, 2] \n\t"), nil
+}
+
+type indentScalar struct {
+	A int     `json:"a"`
+	B string  `json:"b"`
+	C float64 `json:"c"`
+}
+
+type indentOmit struct {
+	A int    `json:"a,omitempty"`
+	B string `json:"b,omitempty"`
+}
+
+// 测试 MarshalIndent 与 encoding/json.MarshalIndent 输出一致
+func TestMarshalIndent(t *testing.T) {
+	orig := GetDefaultConfig()
+	defer SetDefaultConfig(orig)
+	SetDefaultConfig(Config{SortMapKeys: true})
+
+	tests := []struct {
+		name  string
+		input interface{}
+	}{
+		{"scalar", 42},
+		{"empty-slice", []int{}},
+		{"empty-map", map[string]int{}},
+		{"int-slice", []int{1, 2, 3}},
+		{"string-slice", []string{"a", "b"}},
+		{"float-slice", []float64{1.5, 2}},
+		{"nested-slice", [][]int{{1}, {}, {2, 3}}},
+		{"interface", []interface{}{1, "x", nil, map[string]interface{}{"a": []interface{}{}, "b": 2}}},
+		{"map", map[string][]int{"x": {1}, "y": nil, "z": {}}},
+		{"map-single", map[string]interface{}{"only": map[string]interface{}{"v": 1}}},
+		{"struct", NestedStruct{ID: 1, Parent: &EncodeTestStruct{Name: "n", Tags: []string{"a"}, Metadata: map[string]interface{}{"k": 1}}, Children: []EncodeTestStruct{}}},
+		{"opcode-struct", &indentScalar{A: 1, B: "b", C: 1.5}},
+		{"empty-struct", struct{}{}},
+		{"omitempty-all-empty", indentOmit{}},
+		{"omitempty", []indentOmit{{A: 1}, {B: "x"}}},
+		{"marshaler", map[string]interface{}{"m": rawObjectMarshaler{}}},
+		{"padded-marshaler", []interface{}{paddedMarshaler{}, 1}},
+		{"padded-raw", map[string]json.RawMessage{"a": json.RawMessage("[1] \n"), "b": json.RawMessage("\t{\"x\":1}  ")}},
+		{"array", [2][]string{{"a"}, nil}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			for _, indent := range [][2]string{{"", "  "}, {"//", "\t"}} {
+				got, err := MarshalIndent(tc.input, indent[0], indent[1])
+				if err != nil {
+					t.Fatalf("MarshalIndent 失败: %v", err)
+				}
+				expected, _ := json.MarshalIndent(tc.input, indent[0], indent[1])
+				if string(got) != string(expected) {
+					t.Errorf("MarshalIndent = %s, 期望 %s", got, expected)
+				}
+			}
+		})
+	}
+
+	// 缩进状态不应泄漏到后续的 Marshal 调用
+	if got, _ := MarshalString([]int{1, 2}); got != "[1,2]" {
+		t.Errorf("MarshalIndent 之后 Marshal = %s", got)
+	}
+}
+
+// 测试 Indent / Compact 与 encoding/json 输出一致
+func TestIndentCompact(t *testing.T) {
+	inputs := []string{
+		`{"a":[1,2,{"b":null}],"c":{},"d":[],"e":"x\"y\u00e9"}`,
+		"  [ 1 ,\t-0.5e+10 , true , false ]\n\n",
+		`{ "a" : { "b" : [ [ ] , { } ] } }`,
+		`"only string"`,
+		`0`,
+	}
+	for _, in := range inputs {
+		var got, expected bytes.Buffer
+		if err := Indent(&got, []byte(in), "> ", "\t"); err != nil {
+			t.Fatalf("Indent(%q) 失败: %v", in, err)
+		}
+		_ = json.Indent(&expected, []byte(in), "> ", "\t")
+		if got.String() != expected.String() {
+			t.Errorf("Indent(%q) = %q, 期望 %q", in, got.String(), expected.String())
+		}
+
+		got.Reset()
+		expected.Reset()
+		if err := Compact(&got, []byte(in)); err != nil {
+			t.Fatalf("Compact(%q) 失败: %v", in, err)
+		}
+		_ = json.Compact(&expected, []byte(in))
+		if got.String() != expected.String() {
+			t.Errorf("Compact(%q) = %q, 期望 %q", in, got.String(), expected.String())
+		}
+	}
+}
+
+// 测试 Indent / Compact 的语法错误：返回 SyntaxError 且不修改 dst
+func TestIndentCompactErrors(t *testing.T) {
+	tests := []struct {
+		input  string
+		msg    string
+		offset int64
+	}{
+		{`{"a":1`, "unexpected end of JSON input", 6},
+		{`[1,]`, "invalid character ']' looking for beginning of value", 4},
+		{`{"a"}`, "invalid character '}' after object key", 5},
+		{`{"a":tru}`, "invalid character '}' in literal true (expecting 'e')", 9},
+		{`1 x`, "invalid character 'x' after top-level value", 3},
+		{`01`, "invalid character '1' after top-level value", 2},
+		{`[1.e5]`, "invalid character 'e' after decimal point in numeric literal", 4},
+		{`"\q"`, "invalid character 'q' in string escape code", 3},
+		{`{1:2}`, "invalid character '1' looking for beginning of object key string", 2},
+		{`[1 2]`, "invalid character '2' after array value", 4},
+		{`{"a":1 2}`, "invalid character '2' after object key:value pair", 8},
+		{"\"a\x01\"", `invalid character '\x01' in string`, 3},
+	}
+
+	for _, tc := range tests {
+		for _, reformat := range []func(*bytes.Buffer, []byte) error{
+			Compact,
+			func(dst *bytes.Buffer, src []byte) error { return Indent(dst, src, "", "  ") },
+		} {
+			dst := bytes.NewBufferString("keep")
+			err := reformat(dst, []byte(tc.input))
+			var se *SyntaxError
+			if !errors.As(err, &se) {
+				t.Fatalf("%q: 期望 *SyntaxError, 得到 %v", tc.input, err)
+			}
+			if se.Error() != tc.msg || se.Offset != tc.offset {
+				t.Errorf("%q: 错误 = %q@%d, 期望 %q@%d", tc.input, se.Error(), se.Offset, tc.msg, tc.offset)
+			}
+			if dst.String() != "keep" {
+				t.Errorf("%q: 出错后 dst 被修改: %q", tc.input, dst.String())
+			}
+		}
+	}
+}
+
+// htmlMarshaler 的 MarshalJSON 输出包含需要 HTML 转义的字符
+type htmlMarshaler struct{}
+
+func (htmlMarshaler) MarshalJSON() ([]byte, error) {
+	return []byte(`{"html": "<b>&</b>\u2028"}`), nil
+}
+
+// 测试字符串转义选项：三项组合后与 encoding/json 默认输出一致
+func TestMarshalEscapeModes(t *testing.T) {
+	orig := GetDefaultConfig()
+	defer SetDefaultConfig(orig)
+	SetDefaultConfig(Config{SortMapKeys: true, EscapeHTML: true, EscapeLineTerminators: true, InvalidUTF8: InvalidUTF8Replace})
+
+	inputs := []interface{}{
+		"<script>alert('x')</script> & more",
+		"line\u2028sep\u2029end",
+		"中文与 emoji 😀 不需要转义",
+		"a long plain ascii string without anything special in it",
+		map[string]string{"<k>": "v&", "ok": "\u2029"},
+		[]interface{}{"<", map[string]interface{}{"&": htmlMarshaler{}}},
+	}
+	for _, in := range inputs {
+		got, err := Marshal(in)
+		if err != nil {
+			t.Fatalf("Marshal(%q) 失败: %v", in, err)
+		}
+		expected, _ := json.Marshal(in)
+		if string(got) != string(expected) {
+			t.Errorf("Marshal(%q) = %s, 期望 %s", in, got, expected)
+		}
+	}
+
+	if got, _ := MarshalString("bad\xffutf8\xc3("); got != `"bad\ufffdutf8\ufffd("` {
+		t.Errorf("InvalidUTF8Replace = %s", got)
+	}
+
+	SetDefaultConfig(Config{ASCIIOnly: true})
+	asciiTests := []struct {
+		in, out string
+	}{
+		{"plain", `"plain"`},
+		{"é中", `"\u00e9\u4e2d"`},
+		{"😀!", `"\ud83d\ude00!"`},
+		{"x\xffy", `"x\ufffdy"`},
+	}
+	for _, tc := range asciiTests {
+		if got, err := MarshalString(tc.in); err != nil || got != tc.out {
+			t.Errorf("ASCIIOnly Marshal(%q) = %s, %v, 期望 %s", tc.in, got, err, tc.out)
+		}
+	}
+
+	SetDefaultConfig(Config{InvalidUTF8: InvalidUTF8Reject})
+	_, err := Marshal(map[string]string{"k": "ok\xfe"})
+	var ue *InvalidUTF8Error
+	if !errors.As(err, &ue) || ue.S != "ok\xfe" {
+		t.Errorf("期望 *InvalidUTF8Error, 得到 %v", err)
+	}
+	if _, err := Marshal("合法 UTF-8"); err != nil {
+		t.Errorf("合法字符串不应报错: %v", err)
+	}
+
+	// 默认模式保持原有输出
+	SetDefaultConfig(Config{})
+	if got, _ := MarshalString("<&>\u2028"); got != "\"<&>\u2028\"" {
+		t.Errorf("默认模式 = %s", got)
+	}
+}
+
+// escapedNames 的键名含有转义选项会影响的字符
+type escapedNames struct {
+	Less  int            `json:"x<y"`
+	Amp   string         `json:"a&b,omitempty"`
+	Name  string         `json:"名字"`
+	Größe int            `json:",omitempty"`
+	Inner *escapedInner  `json:"in>ner"`
+	Map   map[string]int `json:"m"`
+}
+
+type escapedInner struct {
+	Plain int `json:"plain"`
+	HTML  int `json:"<b>"`
+}
+
+// 测试转义选项同样作用于结构体的键名（与 encoding/json 的 nameEscHTML 一致）
+func TestMarshalEscapedFieldNames(t *testing.T) {
+	v := escapedNames{Less: 1, Amp: "&", Name: "n", Größe: 2, Inner: &escapedInner{1, 2}, Map: map[string]int{"<k>": 3}}
+
+	// EscapeHTML 与 encoding/json 的默认行为一致，键名同样转义
+	for _, in := range []interface{}{v, &v, []escapedNames{v, {}}} {
+		got, err := MarshalWithConfig(in, Config{EscapeHTML: true})
+		if err != nil {
+			t.Fatalf("Marshal 失败: %v", err)
+		}
+		expected, _ := json.Marshal(in)
+		if string(got) != string(expected) {
+			t.Errorf("EscapeHTML Marshal = %s, 期望 %s", got, expected)
+		}
+	}
+
+	tests := []struct {
+		config Config
+		want   string
+	}{
+		{Config{}, `{"x<y":1,"a&b":"&","名字":"n","Größe":2,"in>ner":{"plain":1,"<b>":2},"m":{"<k>":3}}`},
+		{Config{ASCIIOnly: true}, `{"x<y":1,"a&b":"&","\u540d\u5b57":"n","Gr\u00f6\u00dfe":2,"in>ner":{"plain":1,"<b>":2},"m":{"<k>":3}}`},
+	}
+	for _, tc := range tests {
+		if got, err := MarshalWithConfig(v, tc.config); err != nil || string(got) != tc.want {
+			t.Errorf("%+v: Marshal = %s, %v, 期望 %s", tc.config, got, err, tc.want)
+		}
+	}
+
+	// 缩进模式下转义后的键名同样在冒号后加空格
+	got, err := MarshalIndent(escapedInner{HTML: 1}, "", " ")
+	if err != nil || string(got) != "{\n \"plain\": 0,\n \"<b>\": 1\n}" {
+		t.Errorf("MarshalIndent = %q, %v", got, err)
+	}
+}
+
+// 测试 Freeze 得到的 API 及预定义实例
+func TestFreezeAPI(t *testing.T) {
+	inputs := []interface{}{
+		map[string]interface{}{"z": "<a href='x'>&</a>", "a": []interface{}{1.5, " "}, "m": nil},
+		EncodeTestStruct{Name: "<b>", Age: 3, Tags: []string{"&"}, Metadata: map[string]interface{}{"k2": 1, "k1": 2}},
+		[]interface{}{htmlMarshaler{}, map[int]string{3: "c", 1: "a", 2: "b"}},
+		"中文 😀  ",
+	}
+	for _, in := range inputs {
+		got, err := ConfigStd.Marshal(in)
+		if err != nil {
+			t.Fatalf("ConfigStd.Marshal 失败: %v", err)
+		}
+		expected, _ := json.Marshal(in)
+		if string(got) != string(expected) {
+			t.Errorf("ConfigStd.Marshal = %s, 期望 %s", got, expected)
+		}
+	}
+
+	if got, _ := ConfigFastest.MarshalString(math.Pi); got != "3.14159" {
+		t.Errorf("ConfigFastest.MarshalString(Pi) = %s", got)
+	}
+	if ConfigStd.Config().SortMapKeys != true {
+		t.Error("ConfigStd.Config() 应返回固化时的配置")
+	}
+
+	// 各 API 的编码器缓存相互独立
+	type frozenOnly struct{ A int }
+	api := Config{SortMapKeys: true}.Freeze()
+	if _, err := api.Marshal(frozenOnly{A: 1}); err != nil {
+		t.Fatalf("Marshal 失败: %v", err)
+	}
+	typ := reflect.TypeOf(frozenOnly{})
+	if _, ok := api.codecs.encoders.Load(typ); !ok {
+		t.Error("API 自己的缓存中应有该类型的编码器")
+	}
+	if _, ok := EncoderCache.Load(typ); ok {
+		t.Error("API 不应写入全局 EncoderCache")
+	}
+
+	// 全局默认配置的修改不影响已固化的 API
+	orig := GetDefaultConfig()
+	defer SetDefaultConfig(orig)
+	SetDefaultConfig(Config{EscapeHTML: true})
+	if got, _ := api.MarshalString("<"); got != `"<"` {
+		t.Errorf("API 不应受全局配置影响: %s", got)
+	}
+
+	// 流式编解码与 Unmarshal
+	var buf bytes.Buffer
+	enc := api.NewEncoder(&buf)
+	if err := enc.Encode(map[string]int{"b": 2, "a": 1}); err != nil {
+		t.Fatalf("Encode 失败: %v", err)
+	}
+	if buf.String() != "{\"a\":1,\"b\":2}\n" {
+		t.Errorf("Encode = %q", buf.String())
+	}
+	var m map[string]int
+	if err := api.NewDecoder(&buf).Decode(&m); err != nil || m["b"] != 2 {
+		t.Errorf("Decode = %v, %v", m, err)
+	}
+	var s frozenOnly
+	if err := api.Unmarshal([]byte(`{"A":7}`), &s); err != nil || s.A != 7 {
+		t.Errorf("Unmarshal = %+v, %v", s, err)
+	}
+}
+
+// stdKeys 的键名与 RawMessage 内容都受 ConfigStd 的转义与排版影响
+type stdKeys struct {
+	Raw    json.RawMessage            `json:"x<y"`
+	Padded json.RawMessage            `json:"p&q"`
+	Marsh  htmlMarshaler              `json:"m>"`
+	Map    map[string]json.RawMessage `json:"map"`
+	Text   string                     `json:"té"`
+}
+
+// stdPointKey 是实现了 encoding.TextMarshaler 的结构体，可以作为 map 键
+type stdPointKey struct{ X, Y int }
+
+func (p stdPointKey) MarshalText() ([]byte, error) {
+	return []byte(strconv.Itoa(p.X) + "<" + strconv.Itoa(p.Y)), nil
+}
+
+// stdIntKey 是实现了 encoding.TextMarshaler 的整数类型，键名取 MarshalText 的结果
+type stdIntKey int
+
+func (k stdIntKey) MarshalText() ([]byte, error) {
+	return []byte("n" + strconv.Itoa(int(k))), nil
+}
+
+// 测试 ConfigStd 的 Marshal / MarshalIndent 与 encoding/json 逐字节一致（文档列出的例外除外）
+func TestConfigStdMatchesEncodingJSON(t *testing.T) {
+	v := stdKeys{
+		Raw:    json.RawMessage(`{"<a>" : [1, "&"]}`),
+		Padded: json.RawMessage(" \n[1,\t2] \n"),
+		Map:    map[string]json.RawMessage{"z<": json.RawMessage(`" "`), "a&": json.RawMessage(" null ")},
+		Text:   "< >",
+	}
+	inputs := []interface{}{
+		v,
+		&v,
+		struct {
+			R json.RawMessage `json:"x<y"`
+		}{json.RawMessage(`1`)},
+		[]interface{}{v, map[string]interface{}{"<k>": []interface{}{json.RawMessage(" [ ] ")}}},
+		// 超出精确整数范围的浮点数
+		[]interface{}{float64(1 << 60), -float64(1 << 60), 1e19, float32(123456789), float32(1 << 30)},
+		// 实现 encoding.TextMarshaler 的非字符串键
+		map[stdPointKey]int{{1, 2}: 3, {0, 1}: 4},
+		map[stdIntKey][]int{2: {1}, 10: nil},
+		map[*big.Int]string{big.NewInt(5): "five", big.NewInt(12): "twelve"},
+	}
+	for _, in := range inputs {
+		got, err := ConfigStd.Marshal(in)
+		if err != nil {
+			t.Fatalf("ConfigStd.Marshal 失败: %v", err)
+		}
+		expected, _ := json.Marshal(in)
+		if string(got) != string(expected) {
+			t.Errorf("ConfigStd.Marshal = %s, 期望 %s", got, expected)
+		}
+
+		got, err = ConfigStd.MarshalIndent(in, ">", "\t")
+		if err != nil {
+			t.Fatalf("ConfigStd.MarshalIndent 失败: %v", err)
+		}
+		expected, _ = json.MarshalIndent(in, ">", "\t")
+		if string(got) != string(expected) {
+			t.Errorf("ConfigStd.MarshalIndent = %q, 期望 %q", got, expected)
+		}
+	}
+}
+
+// 测试 Canonicalize：RFC 8785 中的示例及排序、数字、错误情况
+func TestCanonicalize(t *testing.T) {
+	tests := []struct {
+		input, output string
+	}{
+		// RFC 8785 §3.2.2 示例
+		{`{
+  "numbers": [333333333.33333329, 1E30, 4.50, 2e-3, 0.000000000000000000000000001],
+  "string": "\u20ac$\u000F\u000aA'\u0042\u0022\u005c\\\"\/",
+  "literals": [null, true, false]
+}`, `{"literals":[null,true,false],"numbers":[333333333.3333333,1e+30,4.5,0.002,1e-27],"string":"€$\u000f\nA'B\"\\\\\"/"}`},
+		// RFC 8785 §3.2.3：按 UTF-16 码元排序，U+1F600（D83D DE00）排在 U+FB33 之前
+		{`{"€":"Euro Sign","\r":"Carriage Return","דּ":"Hebrew Letter Dalet With Dagesh","1":"One","😀":"Emoji: Grinning Face","\u0080":"Control","ö":"Latin Small Letter O With Diaeresis"}`,
+			`{"\r":"Carriage Return","1":"One","` + "\u0080" + `":"Control","` + "ö" + `":"Latin Small Letter O With Diaeresis",` +
+				`"` + "€" + `":"Euro Sign","` + "\U0001F600" + `":"Emoji: Grinning Face","` + "דּ" + `":"Hebrew Letter Dalet With Dagesh"}`},
+		{` { "b" : { "d" : 1 , "c" : [ ] } , "a" : { } } `, `{"a":{},"b":{"c":[],"d":1}}`},
+		{`[-0, 0.0, 1e21, 1e20, 1e-6, 1e-7, 9007199254740993, -1.5E+2, 100]`,
+			`[0,0,1e+21,100000000000000000000,0.000001,1e-7,9007199254740992,-150,100]`},
+		{`"<&> \b\f\u001f\u007f"`, "\"<&> \\b\\f\\u001f\x7f\""},
+		{`"a😀b"`, "\"a\U0001F600b\""},
+	}
+	for _, tc := range tests {
+		got, err := Canonicalize([]byte(tc.input))
+		if err != nil {
+			t.Errorf("Canonicalize(%s) 失败: %v", tc.input, err)
+			continue
+		}
+		if string(got) != tc.output {
+			t.Errorf("Canonicalize(%s) = %s, 期望 %s", tc.input, got, tc.output)
+		}
+		// 规范形式是不动点
+		if again, _ := Canonicalize(got); string(again) != string(got) {
+			t.Errorf("Canonicalize 不是幂等的: %s -> %s", got, again)
+		}
+	}
+
+	errTests := []struct {
+		input string
+		msg   string
+	}{
+		{`{"a":1,"b":2,"a":3}`, `duplicate object key "a"`},
+		{`"\ud800"`, `unpaired surrogate \ud800 in string`},
+		{`"\udc00\ud800"`, `unpaired surrogate \udc00 in string`},
+		{"\"x\xffy\"", "invalid UTF-8 in string"},
+		{`[1e400]`, "number 1e400 out of range"},
+		{`{"a":1`, "unexpected end of JSON input"},
+		{`[1,]`, "invalid character ']' looking for beginning of value"},
+		{`{} x`, "invalid character 'x' after top-level value"},
+	}
+	for _, tc := range errTests {
+		_, err := Canonicalize([]byte(tc.input))
+		var se *SyntaxError
+		if !errors.As(err, &se) || se.Error() != tc.msg {
+			t.Errorf("Canonicalize(%q) 错误 = %v, 期望 %q", tc.input, err, tc.msg)
+		}
+	}
+}
+
+// canonicalKeys 字段声明顺序与规范顺序不同
+type canonicalKeys struct {
+	Zeta  string            `json:"zeta"`
+	Alpha float64           `json:"alpha"`
+	Mid   map[int]string    `json:"mid"`
+	Any   interface{}       `json:"any"`
+	Raw   json.RawMessage   `json:"raw"`
+	Tags  map[string]string `json:"tags,omitempty"`
+}
+
+// 测试 Canonical 编码模式：结构体、map 与 interface{} 树都按规范形式输出
+func TestMarshalCanonical(t *testing.T) {
+	api := Config{Canonical: true, EscapeHTML: true, FloatPrecision: 3}.Freeze()
+	v := canonicalKeys{
+		Zeta:  "<&>",
+		Alpha: -0.0000001,
+		Mid:   map[int]string{10: "ten", 9: "nine", 1: "one"},
+		Any: map[string]interface{}{
+			"y": []interface{}{1.0, math.Copysign(0, -1)},
+			"x": map[string]interface{}{"דּ": 1, "\U0001F600": 2},
+		},
+		Raw: json.RawMessage(`{ "b": 2.50, "a": 1 }`),
+	}
+	want := `{"alpha":-1e-7,"any":{"x":{"` + "\U0001F600" + `":2,"` + "דּ" + `":1},"y":[1,0]},` +
+		`"mid":{"1":"one","10":"ten","9":"nine"},"raw":{"a":1,"b":2.5},"zeta":"<&>"}`
+
+	got, err := api.MarshalString(v)
+	if err != nil {
+		t.Fatalf("Canonical Marshal 失败: %v", err)
+	}
+	if got != want {
+		t.Errorf("Canonical Marshal = %s, 期望 %s", got, want)
+	}
+	if b, err := MarshalWithConfig(&v, Config{Canonical: true}); err != nil || string(b) != want {
+		t.Errorf("MarshalWithConfig = %s, %v", b, err)
+	}
+
+	// 缩进在规范化之后施加
+	indented, err := api.MarshalIndent(map[string]int{"b": 1, "a": 2}, "", " ")
+	if err != nil || string(indented) != "{\n \"a\": 2,\n \"b\": 1\n}" {
+		t.Errorf("Canonical MarshalIndent = %q, %v", indented, err)
+	}
+
+	// 流式编码同样输出完整的规范形式
+	var buf bytes.Buffer
+	if err := api.NewEncoder(&buf).Encode(v); err != nil || buf.String() != want+"\n" {
+		t.Errorf("Canonical Encode = %q, %v", buf.String(), err)
+	}
+
+	// 非法 UTF-8：默认模式报错，InvalidUTF8Replace 时替换
+	if _, err := api.Marshal("bad\xff"); err == nil {
+		t.Error("Canonical 模式下非法 UTF-8 应返回错误")
+	}
+	replace := Config{Canonical: true, InvalidUTF8: InvalidUTF8Replace}.Freeze()
+	if got, err := replace.MarshalString("bad\xff"); err != nil || got != "\"bad�\"" {
+		t.Errorf("InvalidUTF8Replace = %s, %v", got, err)
+	}
+}
+
+// 嵌入指针的测试类型
+type EmbedBase struct {
+	ID   int    `json:"id"`
+	Name string `json:"name,omitempty"`
+}
+
+type embedInner struct {
+	Secret string `json:"secret"`
+}
+
+type EmbedPtrModel struct {
+	*EmbedBase
+	*embedInner
+	Title string `json:"title"`
+}
+
+// 测试经由嵌入指针提升的字段：非 nil 时输出，nil 时整体跳过，与 encoding/json 一致
+func TestMarshalEmbeddedPointer(t *testing.T) {
+	inputs := []interface{}{
+		EmbedPtrModel{EmbedBase: &EmbedBase{ID: 1, Name: "base"}, embedInner: &embedInner{Secret: "s"}, Title: "t"},
+		EmbedPtrModel{EmbedBase: &EmbedBase{ID: 2}, Title: "nil inner"},
+		EmbedPtrModel{Title: "all nil"},
+		&EmbedPtrModel{EmbedBase: &EmbedBase{}},
+		[]EmbedPtrModel{{Title: "a"}, {EmbedBase: &EmbedBase{ID: 3}}},
+		struct{ *EmbedBase }{&EmbedBase{ID: 4}},
+		struct{ *EmbedBase }{},
+	}
+	for _, in := range inputs {
+		got, err := Marshal(in)
+		if err != nil {
+			t.Fatalf("Marshal(%+v) 失败: %v", in, err)
+		}
+		expected, _ := json.Marshal(in)
+		if string(got) != string(expected) {
+			t.Errorf("Marshal(%+v) = %s, 期望 %s", in, got, expected)
+		}
+	}
+}
+
+// ptrZeroer 只有指针接收者实现 IsZero
+type ptrZeroer struct{ N int }
+
+func (z *ptrZeroer) IsZero() bool { return z.N < 0 }
+
+// omitZeroScalars 全部是标量字段，可寻址时走 opcode 路径
+type omitZeroScalars struct {
+	I  int     `json:"i,omitzero"`
+	U  uint8   `json:"u,omitzero"`
+	F  float64 `json:"f,omitzero"`
+	F2 float32 `json:"f2,omitempty"`
+	S  string  `json:"s,omitzero"`
+	B  bool    `json:"b,omitempty"`
+	K  int     `json:"k"`
+}
+
+type omitZeroMixed struct {
+	When    time.Time         `json:"when,omitzero"`
+	WhenPtr *time.Time        `json:"when_ptr,omitzero"`
+	Inner   struct{ A int }   `json:"inner,omitzero"`
+	Arr     [2]int            `json:"arr,omitzero"`
+	Slice   []int             `json:"slice,omitzero"`
+	Map     map[string]int    `json:"map,omitzero"`
+	Both    []int             `json:"both,omitempty,omitzero"`
+	Custom  ptrZeroer         `json:"custom,omitzero"`
+	Iface   fmt.Stringer      `json:"iface,omitzero"`
+	Zeroer  interface{}       `json:"zeroer,omitzero"`
+	Labels  map[string]string `json:"labels,omitempty"`
+	Visible int               `json:"visible"`
+}
+
+// 测试 omitzero：省略零值或 IsZero() 返回 true 的字段，与 encoding/json 一致
+func TestMarshalOmitZero(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	negZero := math.Copysign(0, -1)
+	inputs := []interface{}{
+		omitZeroScalars{},
+		&omitZeroScalars{},
+		&omitZeroScalars{I: 1, U: 2, F: negZero, F2: float32(negZero), S: "s", B: true, K: 3},
+		[]omitZeroScalars{{F: 1.5}, {}},
+		omitZeroMixed{},
+		&omitZeroMixed{},
+		&omitZeroMixed{
+			When:    now,
+			WhenPtr: &time.Time{},
+			Inner:   struct{ A int }{1},
+			Arr:     [2]int{0, 1},
+			Slice:   []int{},
+			Map:     map[string]int{},
+			Both:    []int{},
+			Custom:  ptrZeroer{N: -1},
+			Zeroer:  time.Time{},
+		},
+		omitZeroMixed{Custom: ptrZeroer{N: -1}, Zeroer: now, Visible: 1},
+	}
+	for _, in := range inputs {
+		got, err := Marshal(in)
+		if err != nil {
+			t.Fatalf("Marshal(%+v) 失败: %v", in, err)
+		}
+		expected, _ := json.Marshal(in)
+		if string(got) != string(expected) {
+			t.Errorf("Marshal(%+v) = %s, 期望 %s", in, got, expected)
+		}
+	}
+}
+
+// regID 模拟无法为其添加 MarshalJSON 的第三方类型
+type regID [2]byte
+
+// regTemp 本身实现了 json.Marshaler，注册的编码函数应优先
+type regTemp float64
+
+func (t regTemp) MarshalJSON() ([]byte, error) { return []byte(`"marshaler"`), nil }
+
+type regModel struct {
+	ID     regID            `json:"id"`
+	IDs    []regID          `json:"ids"`
+	Ptr    *regID           `json:"ptr"`
+	Nil    *regID           `json:"nil"`
+	Any    interface{}      `json:"any"`
+	ByName map[string]regID `json:"by_name"`
+	Temp   regTemp          `json:"temp"`
+	Quoted regTemp          `json:"quoted,string"`
+}
+
+func encodeRegID(v interface{}, w *Writer) error {
+	id := v.(regID)
+	return w.WriteString(fmt.Sprintf("%02x%02x", id[0], id[1]))
+}
+
+// 测试 RegisterTypeEncoder：注册的编码函数作用于各种位置，且优先于 Marshaler
+func TestRegisterTypeEncoder(t *testing.T) {
+	api := Config{}.Freeze()
+	api.RegisterTypeEncoder(reflect.TypeOf(regID{}), encodeRegID)
+	api.RegisterTypeEncoder(reflect.TypeOf(regTemp(0)), func(v interface{}, w *Writer) error {
+		return w.WriteFloat64(float64(v.(regTemp)) * 10)
+	})
+
+	id := regID{0xab, 0x01}
+	v := regModel{
+		ID:     id,
+		IDs:    []regID{{1, 2}},
+		Ptr:    &id,
+		Any:    []interface{}{regID{0, 0xff}, regTemp(1)},
+		ByName: map[string]regID{"a": {0, 1}},
+		Temp:   1.5,
+		Quoted: 2,
+	}
+	want := `{"id":"ab01","ids":["0102"],"ptr":"ab01","nil":null,"any":["00ff",10],` +
+		`"by_name":{"a":"0001"},"temp":15,"quoted":20}`
+	if got, err := api.MarshalString(v); err != nil || got != want {
+		t.Errorf("注册编码函数后 Marshal = %s, %v, 期望 %s", got, err, want)
+	}
+
+	// 未注册的 API 与包级函数不受影响，regTemp 仍调用 MarshalJSON（包括位于 interface{} 中时）
+	if got, err := MarshalString([]interface{}{regTemp(1), regID{1, 2}}); err != nil || got != `["marshaler",[1,2]]` {
+		t.Errorf("未注册时 Marshal = %s, %v", got, err)
+	}
+
+	// 缩进模式下 WriteRaw 的内容按当前层级重新缩进
+	indentAPI := Config{}.Freeze()
+	indentAPI.RegisterTypeEncoder(reflect.TypeOf(regID{}), func(v interface{}, w *Writer) error {
+		return w.WriteRaw([]byte(`{"hi":1}`))
+	})
+	if got, err := indentAPI.MarshalIndent([]regID{{}}, "", "  "); err != nil || string(got) != "[\n  {\n    \"hi\": 1\n  }\n]" {
+		t.Errorf("MarshalIndent = %q, %v", got, err)
+	}
+
+	// *T 的方法集包含 T 的值接收者 MarshalJSON，注册的函数对 *T 字段同样优先
+	timeAPI := Config{}.Freeze()
+	timeAPI.RegisterTypeEncoder(reflect.TypeOf(time.Time{}), func(v interface{}, w *Writer) error {
+		w.WriteInt(v.(time.Time).Unix())
+		return nil
+	})
+	ts := time.Unix(1700000000, 0).UTC()
+	timeModel := struct {
+		At    time.Time             `json:"at"`
+		Ptr   *time.Time            `json:"ptr"`
+		Nil   *time.Time            `json:"nil"`
+		Ptrs  []*time.Time          `json:"ptrs"`
+		ByKey map[string]*time.Time `json:"by_key"`
+		Any   interface{}           `json:"any"`
+	}{At: ts, Ptr: &ts, Ptrs: []*time.Time{&ts, nil}, ByKey: map[string]*time.Time{"k": &ts}, Any: &ts}
+	wantTime := `{"at":1700000000,"ptr":1700000000,"nil":null,"ptrs":[1700000000,null],"by_key":{"k":1700000000},"any":1700000000}`
+	if got, err := timeAPI.MarshalString(timeModel); err != nil || got != wantTime {
+		t.Errorf("注册 time.Time 后 Marshal = %s, %v, 期望 %s", got, err, wantTime)
+	}
+	if got, err := timeAPI.MarshalString(&ts); err != nil || got != "1700000000" {
+		t.Errorf("注册 time.Time 后 Marshal(*time.Time) = %s, %v", got, err)
+	}
+
+	// 编码函数返回的错误原样传出
+	errAPI := Config{}.Freeze()
+	errAPI.RegisterTypeEncoder(reflect.TypeOf(regID{}), func(v interface{}, w *Writer) error {
+		return errors.New("boom")
+	})
+	if _, err := errAPI.Marshal(regModel{}); err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Errorf("期望编码函数的错误, 得到 %v", err)
+	}
+
+	// 未命名类型不能注册
+	defer func() {
+		if recover() == nil {
+			t.Error("注册未命名类型应 panic")
+		}
+	}()
+	api.RegisterTypeEncoder(reflect.TypeOf([]int(nil)), encodeRegID)
+}
+
+type namerModel struct {
+	UserID     int
+	HTTPServer string
+	Address2   string
+	Tagged     string `json:"custom"`
+	OmitEmpty  string `json:",omitempty"`
+	namerEmbed
+}
+
+type namerEmbed struct {
+	CreatedAt string
+}
+
+// 测试 FieldNamer：只改写未指定标签名的字段，内置策略与自定义函数
+func TestMarshalFieldNamer(t *testing.T) {
+	v := namerModel{UserID: 1, HTTPServer: "h", Address2: "a", Tagged: "t", namerEmbed: namerEmbed{CreatedAt: "c"}}
+	tests := []struct {
+		namer *FieldNamer
+		want  string
+	}{
+		{nil, `{"UserID":1,"HTTPServer":"h","Address2":"a","custom":"t","CreatedAt":"c"}`},
+		{SnakeCase, `{"user_id":1,"http_server":"h","address2":"a","custom":"t","created_at":"c"}`},
+		{KebabCase, `{"user-id":1,"http-server":"h","address2":"a","custom":"t","created-at":"c"}`},
+		{LowerCamel, `{"userID":1,"httpServer":"h","address2":"a","custom":"t","createdAt":"c"}`},
+		{UpperCamel, `{"UserID":1,"HTTPServer":"h","Address2":"a","custom":"t","CreatedAt":"c"}`},
+		{NewFieldNamer(strings.ToUpper), `{"USERID":1,"HTTPSERVER":"h","ADDRESS2":"a","custom":"t","CREATEDAT":"c"}`},
+	}
+	for _, tc := range tests {
+		config := Config{FieldNamer: tc.namer}
+		if got, err := MarshalStringWithConfig(v, config); err != nil || got != tc.want {
+			t.Errorf("MarshalStringWithConfig = %s, %v, 期望 %s", got, err, tc.want)
+		}
+		if got, err := config.Freeze().MarshalString(&v); err != nil || got != tc.want {
+			t.Errorf("Freeze().MarshalString = %s, %v, 期望 %s", got, err, tc.want)
+		}
+	}
+
+	// 同名冲突按改写后的名字判断：同一深度的 UserID 与 User_ID 都改写为 user_id，都被丢弃
+	type conflict struct {
+		UserID  int
+		User_ID int
+		Name    string
+	}
+	if got, err := MarshalStringWithConfig(conflict{1, 2, "n"}, Config{FieldNamer: SnakeCase}); err != nil || got != `{"name":"n"}` {
+		t.Errorf("冲突字段 = %s, %v", got, err)
+	}
+
+	for name, want := range map[string]string{
+		"ID": "id", "OAuth2Token": "o_auth2_token", "V2API": "v2_api", "User_Name": "user_name", "Ünïcode": "ünïcode",
+	} {
+		if got := SnakeCase.Name(name); got != want {
+			t.Errorf("SnakeCase.Name(%q) = %q, 期望 %q", name, got, want)
+		}
+	}
+	if got := UpperCamel.Name("user_name"); got != "UserName" {
+		t.Errorf("UpperCamel.Name = %q", got)
+	}
+}
+
+// tagKeyEvent 同时用于外部 API（json 标签）与内部事件总线（bus 标签）
+type tagKeyEvent struct {
+	ID       int    `json:"id" bus:"event_id"`
+	Name     string `json:"name"`
+	Secret   string `json:"secret" bus:"-"`
+	Note     string `json:"note,omitempty" bus:",omitempty"`
+	Internal string `json:"-" bus:"internal"`
+	Plain    int
+}
+
+// 测试 TagKey：优先读取指定标签，回退到 json 标签与字段名，两种视图可在同一进程中并存
+func TestMarshalTagKey(t *testing.T) {
+	v := tagKeyEvent{ID: 1, Name: "n", Secret: "s", Internal: "i", Plain: 2}
+	wantJSON := `{"id":1,"name":"n","secret":"s","Plain":2}`
+	wantBus := `{"event_id":1,"name":"n","internal":"i","Plain":2}`
+
+	bus := Config{TagKey: "bus"}
+	for i := 0; i < 2; i++ {
+		if got, err := MarshalString(v); err != nil || got != wantJSON {
+			t.Errorf("json 视图 = %s, %v, 期望 %s", got, err, wantJSON)
+		}
+		if got, err := MarshalStringWithConfig(v, bus); err != nil || got != wantBus {
+			t.Errorf("bus 视图 = %s, %v, 期望 %s", got, err, wantBus)
+		}
+	}
+	if got, err := bus.Freeze().MarshalString(&v); err != nil || got != wantBus {
+		t.Errorf("Freeze 后 bus 视图 = %s, %v", got, err)
+	}
+
+	// 与 FieldNamer 组合：命名策略只作用于两种标签都没有指定名字的字段
+	if got, err := MarshalStringWithConfig(v, Config{TagKey: "bus", FieldNamer: SnakeCase}); err != nil ||
+		got != `{"event_id":1,"name":"n","internal":"i","plain":2}` {
+		t.Errorf("TagKey + FieldNamer = %s, %v", got, err)
+	}
+
+	// 同一份 JSON 按 bus 视图解码
+	var back tagKeyEvent
+	if err := UnmarshalWithConfig([]byte(wantBus), &back, bus); err != nil || back != (tagKeyEvent{ID: 1, Name: "n", Internal: "i", Plain: 2}) {
+		t.Errorf("bus 视图解码 = %+v, %v", back, err)
+	}
+}
+
+type opcodeInner struct {
+	A int     `json:"a"`
+	B *string `json:"b,omitempty"`
+}
+
+type opcodeModel struct {
+	Name     string                 `json:"name"`
+	Inner    opcodeInner            `json:"inner"`
+	InnerPtr *opcodeInner           `json:"inner_ptr"`
+	NilPtr   *opcodeInner           `json:"nil_ptr"`
+	IntPtr   *int                   `json:"int_ptr,omitempty"`
+	PtrPtr   **int                  `json:"ptr_ptr"`
+	Items    []opcodeInner          `json:"items"`
+	Ptrs     []*opcodeInner         `json:"ptrs"`
+	Matrix   [][2]float64           `json:"matrix"`
+	Empty    []int                  `json:"empty,omitempty"`
+	NilSlice []string               `json:"nil_slice"`
+	Zero     []string               `json:"zero,omitzero"`
+	Bytes    []byte                 `json:"bytes"`
+	Array    [3]int8                `json:"array"`
+	Map      map[string]opcodeInner `json:"map"`
+	NilMap   map[string]int         `json:"nil_map"`
+	EmptyMap map[string]int         `json:"empty_map,omitempty"`
+	Any      interface{}            `json:"any"`
+	Time     time.Time              `json:"time"`
+	Temps    []regTemp              `json:"temps"`
+	Quoted   int                    `json:"quoted,string"`
+	Struct   opcodeInner            `json:"struct,omitempty"`
+}
+
+// 测试 opcode 程序覆盖嵌套结构体、指针、切片、数组、map、omitempty 与 Marshaler 字段，
+// 可寻址（指针）与不可寻址（值）两条路径的输出都与 encoding/json 一致
+func TestMarshalOpcodeStruct(t *testing.T) {
+	s, n := "s", 7
+	pn := &n
+	full := opcodeModel{
+		Name:     "full",
+		Inner:    opcodeInner{A: 1, B: &s},
+		InnerPtr: &opcodeInner{A: 2},
+		IntPtr:   &n,
+		PtrPtr:   &pn,
+		Items:    []opcodeInner{{A: 3}, {A: 4, B: &s}},
+		Ptrs:     []*opcodeInner{{A: 5}, nil},
+		Matrix:   [][2]float64{{1.5, -2}, {0, 1e21}},
+		Empty:    []int{},
+		Zero:     []string{},
+		Bytes:    []byte("hi"),
+		Array:    [3]int8{-1, 0, 1},
+		Map:      map[string]opcodeInner{"k": {A: 6}},
+		EmptyMap: map[string]int{},
+		Any:      []interface{}{opcodeInner{A: 8}, regTemp(1)},
+		Time:     time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		Temps:    []regTemp{1, 2},
+		Quoted:   9,
+	}
+	for _, v := range []opcodeModel{full, {}} {
+		want, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, input := range []interface{}{v, &v, []opcodeModel{v}} {
+			got, err := Marshal(input)
+			if _, isSlice := input.([]opcodeModel); isSlice {
+				want = append(append([]byte{'['}, want...), ']')
+			}
+			if err != nil || string(got) != string(want) {
+				t.Errorf("Marshal(%T) =\n%s, %v\n期望\n%s", input, got, err, want)
+			}
+		}
+	}
+
+	// 缩进输出同样一致
+	want, _ := json.MarshalIndent(&full, "", "  ")
+	if got, err := MarshalIndent(&full, "", "  "); err != nil || string(got) != string(want) {
+		t.Errorf("MarshalIndent =\n%s, %v\n期望\n%s", got, err, want)
+	}
+
+	// 嵌套元素的错误带上完整路径
+	bad := struct {
+		Items []opcodeInner `json:"items"`
+		F     []float64     `json:"f"`
+	}{F: []float64{1, math.NaN()}}
+	var uve *UnsupportedValueError
+	if _, err := Marshal(&bad); !errors.As(err, &uve) || uve.Path != "f[1]" {
+		t.Errorf("期望路径 f[1] 的 UnsupportedValueError, 得到 %v", err)
+	}
+}
+
+// chunkWriter 记录每次 Write 的长度，可在第 failAt 次写入时返回错误
+type chunkWriter struct {
+	bytes.Buffer
+	writes []int
+	failAt int
+}
+
+var errChunkWrite = errors.New("write failed")
+
+func (w *chunkWriter) Write(p []byte) (int, error) {
+	w.writes = append(w.writes, len(p))
+	if w.failAt > 0 && len(w.writes) >= w.failAt {
+		return 0, errChunkWrite
+	}
+	return w.Buffer.Write(p)
+}
+
+func TestStreamEncoder(t *testing.T) {
+	orig := GetDefaultConfig()
+	defer SetDefaultConfig(orig)
+	SetDefaultConfig(Config{SortMapKeys: true})
+
+	values := []interface{}{
+		1,
+		"<b>&</b>",
+		[]int{1, 2, 3},
+		map[string]interface{}{"a": []interface{}{1, "x"}, "b": map[string]int{}},
+		EncodeTestStruct{Name: "test", Age: 30, Tags: []string{"a", "b"}},
+		nil,
+	}
+
+	cases := []struct {
+		name           string
+		prefix, indent string
+		escapeHTML     bool
+	}{
+		{"compact", "", "", false},
+		{"indent", ">", "\t", false},
+		{"escapeHTML", "", "  ", true},
+	}
+	for _, tc := range cases {
+		var got, expected bytes.Buffer
+		enc := NewStreamEncoder(&got)
+		enc.SetIndent(tc.prefix, tc.indent)
+		enc.SetEscapeHTML(tc.escapeHTML)
+		std := json.NewEncoder(&expected)
+		std.SetIndent(tc.prefix, tc.indent)
+		std.SetEscapeHTML(tc.escapeHTML)
+		for _, v := range values {
+			if err := enc.Encode(v); err != nil {
+				t.Fatalf("%s: Encode 失败: %v", tc.name, err)
+			}
+			_ = std.Encode(v)
+		}
+		if got.String() != expected.String() {
+			t.Errorf("%s: Encode = %s, 期望 %s", tc.name, got.String(), expected.String())
+		}
+	}
+}
+
+func TestStreamEncoderChunked(t *testing.T) {
+	items := make([]EncodeTestStruct, 5000)
+	for i := range items {
+		items[i] = EncodeTestStruct{Name: "item", Age: i, Tags: []string{"a"}}
+	}
+
+	w := &chunkWriter{}
+	if err := NewStreamEncoder(w).Encode(items); err != nil {
+		t.Fatalf("Encode 失败: %v", err)
+	}
+	expected, _ := Marshal(items)
+	if w.String() != string(expected)+"\n" {
+		t.Fatalf("分块输出与 Marshal 不一致")
+	}
+	if len(w.writes) < 2 {
+		t.Errorf("期望分多次写出, 实际 %d 次", len(w.writes))
+	}
+	for _, n := range w.writes {
+		if n > 2*streamFlushThreshold {
+			t.Errorf("单次写出 %d 字节, 超过阈值过多", n)
+		}
+	}
+
+	// 写入失败后错误保持不变
+	w = &chunkWriter{failAt: 2}
+	enc := NewStreamEncoder(w)
+	if err := enc.Encode(items); err != errChunkWrite {
+		t.Fatalf("期望写入错误, 得到 %v", err)
+	}
+	if err := enc.Encode(1); err != errChunkWrite {
+		t.Errorf("错误应保持, 得到 %v", err)
+	}
+	if len(w.writes) != 2 {
+		t.Errorf("出错后不应继续写出, 实际写了 %d 次", len(w.writes))
+	}
+
+	// 编码错误直接返回，编码器仍可继续使用
+	w = &chunkWriter{}
+	enc = NewStreamEncoder(w)
+	if err := enc.Encode(math.NaN()); err == nil {
+		t.Error("NaN 应返回错误")
+	}
+	if err := enc.Encode(1); err != nil || w.String() != "1\n" {
+		t.Errorf("Encode(1) = %q, %v", w.String(), err)
+	}
+}
+
 // 测试深度嵌套结构体的编码性能
 func BenchmarkDeepNestedStruct(b *testing.B) {
 	// 创建深度嵌套的测试数据
@@ -0,0 +1,263 @@
+package sjson
+
+import (
+	"bytes"
+	"sort"
+	"unicode"
+	"unicode/utf8"
+)
+
+// fieldTable 是字段名上的最小完美哈希（hash-and-displace）：键的哈希先定位桶，
+// 再用桶的位移值 seeds[b] 重新混合得到槽位，n 个字段恰好占满 n 个槽位。
+// 查找时只计算一次键的哈希、做两次乘法取槽，与字段数无关；槽位中的字段仍需校验名字
+type fieldTable struct {
+	seeds []uint64 // 每个桶的位移值
+	index []int32  // 槽位 → 字段下标
+}
+
+// 构造时为单个桶寻找位移值的最大尝试次数，超过后放弃建表，查找退回线性扫描
+const maxFieldTableSeed = 1 << 16
+
+// newFieldTable 为互不相同的哈希值构造完美哈希表，hashes[i] 对应字段 i。
+// 哈希值有重复（不同字段名碰撞）或找不到位移值时返回 nil
+func newFieldTable(hashes []uint64, fieldIndex []int32) *fieldTable {
+	n := len(hashes)
+	if n == 0 {
+		return nil
+	}
+	t := &fieldTable{seeds: make([]uint64, (n+1)/2), index: make([]int32, n)}
+
+	buckets := make([][]int, len(t.seeds))
+	for i, h := range hashes {
+		b := reduceHash(h, len(t.seeds))
+		buckets[b] = append(buckets[b], i)
+	}
+	order := make([]int, len(buckets))
+	for i := range order {
+		order[i] = i
+	}
+	// 先放置大桶：此时空槽最多，最容易找到位移值
+	sort.SliceStable(order, func(i, j int) bool { return len(buckets[order[i]]) > len(buckets[order[j]]) })
+
+	used := make([]bool, n)
+	slots := make([]int, 0, 8)
+	for _, b := range order {
+		keys := buckets[b]
+		if len(keys) == 0 {
+			break
+		}
+		seed := uint64(0)
+	search:
+		for ; seed < maxFieldTableSeed; seed++ {
+			slots = slots[:0]
+			for _, k := range keys {
+				s := reduceHash(mixHash(hashes[k]^seed), n)
+				if used[s] {
+					continue search
+				}
+				for _, prev := range slots {
+					if prev == s {
+						continue search
+					}
+				}
+				slots = append(slots, s)
+			}
+			break
+		}
+		if seed == maxFieldTableSeed {
+			return nil
+		}
+		t.seeds[b] = seed
+		for i, k := range keys {
+			used[slots[i]] = true
+			t.index[slots[i]] = fieldIndex[k]
+		}
+	}
+	return t
+}
+
+// lookup 返回哈希为 h 的键可能对应的字段下标，调用方需校验名字
+//
+//go:inline
+func (t *fieldTable) lookup(h uint64) int {
+	b := reduceHash(h, len(t.seeds))
+	return int(t.index[reduceHash(mixHash(h^t.seeds[b]), len(t.index))])
+}
+
+// reduceHash 把哈希值映射到 [0, n)，用乘法代替取模
+func reduceHash(h uint64, n int) int {
+	return int((h >> 32) * uint64(n) >> 32)
+}
+
+// mixHash 是 splitmix64 的终混函数
+func mixHash(h uint64) uint64 {
+	h ^= h >> 30
+	h *= 0xbf58476d1ce4e5b9
+	h ^= h >> 27
+	h *= 0x94d049bb133111eb
+	h ^= h >> 31
+	return h
+}
+
+// fieldNameHash 按 8 字节分块计算字段名的哈希。len<=8 的名字只需一次 head8，
+// 与 structField.nameHead 的取法一致
+func fieldNameHash(b []byte) uint64 {
+	h := uint64(len(b)) * 0x9e3779b97f4a7c15
+	for len(b) > 8 {
+		h = (h ^ head8(b)) * 0xff51afd7ed558ccd
+		b = b[8:]
+	}
+	return mixHash(h ^ head8(b))
+}
+
+// foldFieldNameHash 与 fieldNameHash 相同，但先把每块中的 ASCII 小写字母转为大写。
+// 对纯 ASCII 的键，结果等于 fieldNameHash(foldName(key))，无需物化折叠后的名字；
+// ascii 报告键是否为纯 ASCII，否则哈希值无效
+func foldFieldNameHash(b []byte) (h uint64, ascii bool) {
+	h = uint64(len(b)) * 0x9e3779b97f4a7c15
+	var seen uint64
+	for len(b) > 8 {
+		chunk := head8(b)
+		seen |= chunk
+		h = (h ^ upperASCII8(chunk)) * 0xff51afd7ed558ccd
+		b = b[8:]
+	}
+	chunk := head8(b)
+	seen |= chunk
+	return mixHash(h ^ upperASCII8(chunk)), seen&0x8080808080808080 == 0
+}
+
+// upperASCII8 用 SWAR 把 8 个字节中的 'a'-'z' 转为大写，其余字节不变
+func upperASCII8(x uint64) uint64 {
+	const (
+		ones = 0x0101010101010101
+		high = 0x8080808080808080
+	)
+	low7 := x &^ high
+	gea := low7 + (0x80-'a')*ones   // 字节 >= 'a' 时最高位为 1
+	gtz := low7 + (0x80-'z'-1)*ones // 字节 > 'z' 时最高位为 1
+	lower := gea &^ gtz &^ x & high // 再排除最高位本来为 1 的非 ASCII 字节
+	return x &^ (lower >> 2)
+}
+
+// foldName 返回与 encoding/json 相同的大小写折叠形式：ASCII 字母转为大写，
+// 其他字符取其 Unicode 简单折叠集合中最小的码点（如 K 的开尔文符号折叠为 'K'）。
+// 折叠形式相同的两个名字即大小写不敏感地相等
+func foldName(out, in []byte) []byte {
+	for i := 0; i < len(in); {
+		if c := in[i]; c < utf8.RuneSelf {
+			if 'a' <= c && c <= 'z' {
+				c -= 'a' - 'A'
+			}
+			out = append(out, c)
+			i++
+			continue
+		}
+		r, n := utf8.DecodeRune(in[i:])
+		out = utf8.AppendRune(out, foldRune(r))
+		i += n
+	}
+	return out
+}
+
+// foldRune 返回 r 所在简单折叠集合中最小的码点
+func foldRune(r rune) rune {
+	for {
+		r2 := unicode.SimpleFold(r)
+		if r2 <= r {
+			return r2
+		}
+		r = r2
+	}
+}
+
+// fieldLookup 是结构体的字段查找表：exact 按原名，folded 按 foldName 折叠后的名字。
+// 折叠后同名的字段只保留声明在前的一个（与 encoding/json 一致）
+type fieldLookup struct {
+	exact  *fieldTable
+	folded *fieldTable
+	folds  [][]byte // 每个字段名的折叠形式
+}
+
+func newFieldLookup(fields []structField) fieldLookup {
+	l := fieldLookup{folds: make([][]byte, len(fields))}
+	hashes := make([]uint64, 0, len(fields))
+	indexes := make([]int32, 0, len(fields))
+	for i := range fields {
+		hashes = append(hashes, fieldNameHash(fields[i].name))
+		indexes = append(indexes, int32(i))
+		l.folds[i] = foldName(nil, fields[i].name)
+	}
+	if !hasDuplicateHash(hashes) {
+		l.exact = newFieldTable(hashes, indexes)
+	}
+
+	hashes, indexes = hashes[:0], indexes[:0]
+outer:
+	for i := range fields {
+		for _, j := range indexes {
+			if bytes.Equal(l.folds[i], l.folds[j]) {
+				continue outer
+			}
+		}
+		hashes = append(hashes, fieldNameHash(l.folds[i]))
+		indexes = append(indexes, int32(i))
+	}
+	if !hasDuplicateHash(hashes) {
+		l.folded = newFieldTable(hashes, indexes)
+	}
+	return l
+}
+
+func hasDuplicateHash(hashes []uint64) bool {
+	sorted := append([]uint64(nil), hashes...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	for i := 1; i < len(sorted); i++ {
+		if sorted[i] == sorted[i-1] {
+			return true
+		}
+	}
+	return false
+}
+
+// find 返回与键精确匹配的字段下标，找不到返回 -1。查找表未能建立时退回线性扫描
+func (l *fieldLookup) find(fields []structField, key []byte) int {
+	if l.exact == nil {
+		return exactFieldIndex(fields, key)
+	}
+	i := l.exact.lookup(fieldNameHash(key))
+	if f := &fields[i]; f.nameLen == len(key) && f.nameHead == head8(key) &&
+		(len(key) <= 8 || bytes.Equal(f.name, key)) {
+		return i
+	}
+	return -1
+}
+
+// findFold 返回与键大小写不敏感匹配的字段下标，找不到返回 -1。
+// 纯 ASCII 的键直接按块折叠计算哈希；其余的键先折叠到栈上的缓冲区
+func (l *fieldLookup) findFold(key []byte) int {
+	if l.folded != nil {
+		if h, ascii := foldFieldNameHash(key); ascii {
+			i := l.folded.lookup(h)
+			if equalFoldASCII(key, l.folds[i]) {
+				return i
+			}
+			return -1
+		}
+	}
+
+	var buf [64]byte
+	folded := foldName(buf[:0], key)
+	if l.folded != nil {
+		if i := l.folded.lookup(fieldNameHash(folded)); bytes.Equal(folded, l.folds[i]) {
+			return i
+		}
+		return -1
+	}
+	for i, f := range l.folds {
+		if bytes.Equal(folded, f) {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,457 @@
+package sjson
+
+import (
+	"bytes"
+	"strconv"
+	"unsafe"
+)
+
+// SyntaxError 表示 Indent / Compact 输入的 JSON 语法错误。错误信息沿用 encoding/json 的格式
+// （invalid character 'x' after array value 等），具体措辞在不同 Go 版本的标准库之间略有差异
+type SyntaxError struct {
+	msg    string
+	Offset int64 // 读取到 Offset 字节后发现错误
+}
+
+func (e *SyntaxError) Error() string { return e.msg }
+
+// Compact 将 src 去除无意义空白后追加到 dst。出错时 dst 保持不变。
+func Compact(dst *bytes.Buffer, src []byte) error {
+	dst.Grow(len(src))
+	b, err := appendCompact(dst.AvailableBuffer(), src)
+	if err != nil {
+		return err
+	}
+	dst.Write(b)
+	return nil
+}
+
+// Indent 将 src 按缩进格式追加到 dst：每个元素另起一行，行首为 prefix，之后按嵌套层数重复 indent。
+// 与 encoding/json 一致，src 开头的空白被丢弃、末尾的空白原样保留，出错时 dst 保持不变。
+func Indent(dst *bytes.Buffer, src []byte, prefix, indent string) error {
+	dst.Grow(len(src))
+	b, err := appendIndent(dst.AvailableBuffer(), src, prefix, indent)
+	if err != nil {
+		return err
+	}
+	dst.Write(b)
+	return nil
+}
+
+func appendCompact(dst, src []byte) ([]byte, error) {
+	r := reformatter{dst: dst, src: src}
+	return r.run()
+}
+
+func appendIndent(dst, src []byte, prefix, indent string) ([]byte, error) {
+	r := reformatter{dst: dst, src: src, indenting: true, prefix: prefix, indent: indent, keepTrailing: true}
+	return r.run()
+}
+
+// reformat 的语法状态
+const (
+	reformatValue      = iota // 期望一个值
+	reformatFirstElem         // '[' 之后：值或 ']'
+	reformatFirstKey          // '{' 之后：键或 '}'
+	reformatKey               // 对象中 ',' 之后：键
+	reformatColon             // 键之后：':'
+	reformatObjectNext        // 键值对之后：',' 或 '}'
+	reformatArrayNext         // 元素之后：',' 或 ']'
+	reformatDone              // 顶层值结束
+)
+
+// reformatter 逐个词法单元校验 src 并写出紧凑或缩进格式。
+// 字符串与数字原样复制，空白用 lexer 同款的 8 字节批量判断跳过。
+type reformatter struct {
+	dst []byte
+	src []byte
+	pos int
+
+	indenting  bool
+	prefix     string
+	indent     string
+	depth      int
+	needIndent bool   // 刚写入 '{' 或 '['，等确认非空后再换行
+	stack      []byte // 未闭合的 '{' / '['
+
+	// keepTrailing 原样保留顶层值之后的空白，仅用于公开的 Indent；
+	// 编码时嵌入的 MarshalJSON / RawMessage 内容丢弃这些空白（与 encoding/json 一致）
+	keepTrailing bool
+
+	// escape 只取 escapeHTML / escapeLineTerminators，对字符串内容做与 encoding/json 相同的补充转义
+	escape escapeFlags
+}
+
+func (r *reformatter) run() ([]byte, error) {
+	origLen := len(r.dst)
+	if err := r.reformat(); err != nil {
+		return r.dst[:origLen], err
+	}
+	return r.dst, nil
+}
+
+func (r *reformatter) reformat() error {
+	state := reformatValue
+	for state != reformatDone {
+		r.skipSpace()
+		if r.pos >= len(r.src) {
+			return r.eofError()
+		}
+		c := r.src[r.pos]
+
+		switch state {
+		case reformatColon:
+			if c != ':' {
+				return r.charError(c, "after object key")
+			}
+			r.pos++
+			r.dst = append(r.dst, ':')
+			if r.indenting {
+				r.dst = append(r.dst, ' ')
+			}
+			state = reformatValue
+			continue
+
+		case reformatObjectNext, reformatArrayNext:
+			closer, next, context := byte('}'), reformatKey, "after object key:value pair"
+			if state == reformatArrayNext {
+				closer, next, context = ']', reformatValue, "after array value"
+			}
+			switch c {
+			case ',':
+				r.pos++
+				r.dst = append(r.dst, ',')
+				if r.indenting {
+					r.newline()
+				}
+				state = next
+			case closer:
+				r.pos++
+				state = r.closeContainer(c)
+			default:
+				return r.charError(c, context)
+			}
+			continue
+
+		case reformatFirstKey, reformatKey:
+			if c == '}' && state == reformatFirstKey {
+				r.pos++
+				state = r.closeContainer(c)
+				continue
+			}
+			if c != '"' {
+				return r.charError(c, "looking for beginning of object key string")
+			}
+			r.beginElem()
+			if err := r.copyString(); err != nil {
+				return err
+			}
+			state = reformatColon
+			continue
+
+		case reformatFirstElem:
+			if c == ']' {
+				r.pos++
+				state = r.closeContainer(c)
+				continue
+			}
+		}
+
+		// 期望一个值
+		r.beginElem()
+		var err error
+		switch c {
+		case '{', '[':
+			r.pos++
+			r.dst = append(r.dst, c)
+			r.stack = append(r.stack, c)
+			r.needIndent = r.indenting
+			if c == '{' {
+				state = reformatFirstKey
+			} else {
+				state = reformatFirstElem
+			}
+			continue
+		case '"':
+			err = r.copyString()
+		case '-', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
+			err = r.copyNumber()
+		case 't':
+			err = r.copyLiteral("true")
+		case 'f':
+			err = r.copyLiteral("false")
+		case 'n':
+			err = r.copyLiteral("null")
+		default:
+			return r.charError(c, "looking for beginning of value")
+		}
+		if err != nil {
+			return err
+		}
+		state = r.afterValue()
+	}
+
+	// 顶层值之后只允许空白：Indent 原样保留，Compact 与嵌入编码结果时丢弃
+	start := r.pos
+	r.skipSpace()
+	if r.pos < len(r.src) {
+		return r.charError(r.src[r.pos], "after top-level value")
+	}
+	if r.keepTrailing {
+		r.dst = append(r.dst, r.src[start:]...)
+	}
+	return nil
+}
+
+// afterValue 根据所在容器返回一个值结束后的状态
+func (r *reformatter) afterValue() int {
+	if len(r.stack) == 0 {
+		return reformatDone
+	}
+	if r.stack[len(r.stack)-1] == '{' {
+		return reformatObjectNext
+	}
+	return reformatArrayNext
+}
+
+// beginElem 容器的第一个元素前补上延迟的换行
+func (r *reformatter) beginElem() {
+	if r.needIndent {
+		r.needIndent = false
+		r.depth++
+		r.newline()
+	}
+}
+
+func (r *reformatter) closeContainer(c byte) int {
+	if r.indenting {
+		if r.needIndent {
+			// 空对象/空数组保持 {} / []
+			r.needIndent = false
+		} else {
+			r.depth--
+			r.newline()
+		}
+	}
+	r.dst = append(r.dst, c)
+	r.stack = r.stack[:len(r.stack)-1]
+	return r.afterValue()
+}
+
+func (r *reformatter) newline() {
+	r.dst = append(r.dst, '\n')
+	r.dst = append(r.dst, r.prefix...)
+	for i := 0; i < r.depth; i++ {
+		r.dst = append(r.dst, r.indent...)
+	}
+}
+
+func (r *reformatter) skipSpace() {
+	src := r.src
+	pos := r.pos
+	for pos+8 <= len(src) {
+		if !isAllWhitespace8(*(*uint64)(unsafe.Pointer(&src[pos]))) {
+			break
+		}
+		pos += 8
+	}
+	for pos < len(src) {
+		if c := src[pos]; c != ' ' && c != '\n' && c != '\t' && c != '\r' {
+			break
+		}
+		pos++
+	}
+	r.pos = pos
+}
+
+// copyString 校验并原样复制一个字符串（含引号）
+func (r *reformatter) copyString() error {
+	src := r.src
+	start := r.pos
+	pos := start + 1
+	for {
+		// 8 字节批量跳过不含引号、反斜杠和控制字符的内容
+		for pos+8 <= len(src) {
+			chunk := *(*uint64)(unsafe.Pointer(&src[pos]))
+			if hasBytes8(chunk, 0x2222222222222222) || hasBytes8(chunk, 0x5C5C5C5C5C5C5C5C) || hasControlChars8(chunk) {
+				break
+			}
+			pos += 8
+		}
+		if pos >= len(src) {
+			r.pos = pos
+			return r.eofError()
+		}
+
+		c := src[pos]
+		switch {
+		case c == '"':
+			pos++
+			if r.escape == 0 {
+				r.dst = append(r.dst, src[start:pos]...)
+			} else {
+				r.dst = appendEscapedHTML(r.dst, src[start:pos], r.escape)
+			}
+			r.pos = pos
+			return nil
+		case c == '\\':
+			pos++
+			if pos >= len(src) {
+				r.pos = pos
+				return r.eofError()
+			}
+			switch src[pos] {
+			case '"', '\\', '/', 'b', 'f', 'n', 'r', 't':
+				pos++
+			case 'u':
+				pos++
+				for i := 0; i < 4; i++ {
+					if pos >= len(src) {
+						r.pos = pos
+						return r.eofError()
+					}
+					if !isHexDigit(src[pos]) {
+						r.pos = pos
+						return r.charError(src[pos], "in \\u hexadecimal character escape")
+					}
+					pos++
+				}
+			default:
+				r.pos = pos
+				return r.charError(src[pos], "in string escape code")
+			}
+		case c < 0x20:
+			r.pos = pos
+			return r.charError(c, "in string")
+		default:
+			pos++
+		}
+	}
+}
+
+// copyNumber 按 JSON 数字语法校验并原样复制
+func (r *reformatter) copyNumber() error {
+	src := r.src
+	start := r.pos
+	pos := start
+
+	if src[pos] == '-' {
+		pos++
+	}
+	if pos >= len(src) {
+		r.pos = pos
+		return r.eofError()
+	}
+	switch c := src[pos]; {
+	case c == '0':
+		pos++
+	case c >= '1' && c <= '9':
+		pos = skipDigits(src, pos+1)
+	default:
+		r.pos = pos
+		return r.charError(c, "in numeric literal")
+	}
+
+	if pos < len(src) && src[pos] == '.' {
+		pos++
+		if pos >= len(src) {
+			r.pos = pos
+			return r.eofError()
+		}
+		if c := src[pos]; c < '0' || c > '9' {
+			r.pos = pos
+			return r.charError(c, "after decimal point in numeric literal")
+		}
+		pos = skipDigits(src, pos+1)
+	}
+
+	if pos < len(src) && (src[pos] == 'e' || src[pos] == 'E') {
+		pos++
+		if pos < len(src) && (src[pos] == '+' || src[pos] == '-') {
+			pos++
+		}
+		if pos >= len(src) {
+			r.pos = pos
+			return r.eofError()
+		}
+		if c := src[pos]; c < '0' || c > '9' {
+			r.pos = pos
+			return r.charError(c, "in exponent of numeric literal")
+		}
+		pos = skipDigits(src, pos+1)
+	}
+
+	r.dst = append(r.dst, src[start:pos]...)
+	r.pos = pos
+	return nil
+}
+
+// appendEscapedHTML 复制已校验的字符串字面量，并转义 <、>、& 及 U+2028、U+2029
+func appendEscapedHTML(dst, s []byte, flags escapeFlags) []byte {
+	start := 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if flags&escapeHTML != 0 && (c == '<' || c == '>' || c == '&') {
+			dst = append(dst, s[start:i]...)
+			dst = append(dst, '\\', 'u', '0', '0', lowerHex[c>>4], lowerHex[c&0xF])
+			start = i + 1
+		}
+		// U+2028 / U+2029 的 UTF-8 编码为 E2 80 A8 / E2 80 A9
+		if flags&escapeLineTerminators != 0 && c == 0xE2 && i+2 < len(s) && s[i+1] == 0x80 && s[i+2]&^1 == 0xA8 {
+			dst = append(dst, s[start:i]...)
+			dst = append(dst, '\\', 'u', '2', '0', '2', lowerHex[s[i+2]&0xF])
+			i += 2
+			start = i + 1
+		}
+	}
+	return append(dst, s[start:]...)
+}
+
+func skipDigits(src []byte, pos int) int {
+	for pos < len(src) && src[pos] >= '0' && src[pos] <= '9' {
+		pos++
+	}
+	return pos
+}
+
+func isHexDigit(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+// copyLiteral 校验并复制 true / false / null
+func (r *reformatter) copyLiteral(lit string) error {
+	for i := 1; i < len(lit); i++ {
+		pos := r.pos + i
+		if pos >= len(r.src) {
+			r.pos = pos
+			return r.eofError()
+		}
+		if r.src[pos] != lit[i] {
+			r.pos = pos
+			return r.charError(r.src[pos], "in literal "+lit+" (expecting "+quoteChar(lit[i])+")")
+		}
+	}
+	r.dst = append(r.dst, lit...)
+	r.pos += len(lit)
+	return nil
+}
+
+func (r *reformatter) charError(c byte, context string) error {
+	return &SyntaxError{msg: "invalid character " + quoteChar(c) + " " + context, Offset: int64(r.pos + 1)}
+}
+
+func (r *reformatter) eofError() error {
+	return &SyntaxError{msg: "unexpected end of JSON input", Offset: int64(len(r.src))}
+}
+
+// quoteChar 按 encoding/json 的格式引用出错字符
+func quoteChar(c byte) string {
+	if c == '\'' {
+		return `'\''`
+	}
+	if c == '"' {
+		return `'"'`
+	}
+	s := strconv.Quote(string(rune(c)))
+	return "'" + s[1:len(s)-1] + "'"
+}
@@ -1,13 +1,37 @@
 package sjson
 
 import (
+	"io"
 	"reflect"
 	"sync"
+	"unsafe"
 )
 
 // 编码器流对象池，用于减少内存分配
 type encoderStream struct {
 	buffer []byte
+
+	// 环检测状态（与 encoding/json 相同）：ptrLevel 记录当前指针/map/切片的嵌套层数，
+	// 超过 startDetectingCyclesAfter 后才把已访问的引用记入 ptrSeen
+	ptrLevel uint
+	ptrSeen  map[interface{}]struct{}
+
+	// config 为本次编码使用的配置快照，编码器只从这里读取配置，不访问全局默认配置；
+	// escape 为其中字符串转义选项的位标记（Config.escapeFlags），0 表示最快的默认模式；
+	// codecs 为所属 API 的编解码器缓存
+	config Config
+	escape escapeFlags
+	codecs *codecCache
+
+	// 缩进状态（MarshalIndent）：indenting 为 false 时各编码器只多一次分支判断
+	indenting bool
+	prefix    string
+	indent    string
+	depth     int
+
+	// 流式输出（StreamEncoder）：w 非 nil 时缓冲区在元素边界处分块写出，werr 记录首个写入错误
+	w    io.Writer
+	werr error
 }
 
 var encoderStreamPool = sync.Pool{
@@ -19,13 +43,144 @@ var encoderStreamPool = sync.Pool{
 	},
 }
 
-// 获取一个编码器流
-func getEncoderStream() *encoderStream {
-	return encoderStreamPool.Get().(*encoderStream)
+// startDetectingCyclesAfter 浅层值只需一次计数器自增，不付出 map 查找的代价
+const startDetectingCyclesAfter = 1000
+
+// enterRef 进入一层指针/map/切片引用。嵌套较深时记录已访问的引用，
+// 重复访问即说明存在环；返回 nil 时调用方必须在编码结束后调用 leaveRef。
+func (s *encoderStream) enterRef(v reflect.Value) error {
+	if s.ptrLevel++; s.ptrLevel > startDetectingCyclesAfter {
+		key := cycleKey(v)
+		if _, ok := s.ptrSeen[key]; ok {
+			s.ptrLevel--
+			return &UnsupportedValueError{Value: v, Str: "encountered a cycle via " + v.Type().String(), noPath: true}
+		}
+		if s.ptrSeen == nil {
+			s.ptrSeen = make(map[interface{}]struct{})
+		}
+		s.ptrSeen[key] = struct{}{}
+	}
+	return nil
+}
+
+// leaveRef 离开 enterRef 进入的引用
+func (s *encoderStream) leaveRef(v reflect.Value) {
+	if s.ptrLevel > startDetectingCyclesAfter {
+		delete(s.ptrSeen, cycleKey(v))
+	}
+	s.ptrLevel--
+}
+
+// enterRefAt 与 enterRef 相同，供 opcode 程序按地址调用：p 指向类型为 typ 的指针或切片。
+// 只有嵌套足够深、需要记录引用时才构造 reflect.Value
+func (s *encoderStream) enterRefAt(typ reflect.Type, p unsafe.Pointer) error {
+	if s.ptrLevel >= startDetectingCyclesAfter {
+		return s.enterRef(reflect.NewAt(typ, p).Elem())
+	}
+	s.ptrLevel++
+	return nil
+}
+
+// leaveRefAt 离开 enterRefAt 进入的引用
+func (s *encoderStream) leaveRefAt(typ reflect.Type, p unsafe.Pointer) {
+	if s.ptrLevel > startDetectingCyclesAfter {
+		s.leaveRef(reflect.NewAt(typ, p).Elem())
+		return
+	}
+	s.ptrLevel--
+}
+
+// sliceCycleKey 切片以 (数据指针, 长度) 作为环检测键，与 encoding/json 一致
+type sliceCycleKey struct {
+	ptr unsafe.Pointer
+	len int
+}
+
+func cycleKey(v reflect.Value) interface{} {
+	if v.Kind() == reflect.Slice {
+		return sliceCycleKey{v.UnsafePointer(), v.Len()}
+	}
+	return v.UnsafePointer()
+}
+
+// openComposite 写入 '{' 或 '['
+func (s *encoderStream) openComposite(c byte) {
+	s.buffer = append(s.buffer, c)
+	if s.indenting {
+		s.depth++
+	}
+}
+
+// elemSep 写入第 i 个元素（或键值对）之前的分隔符，缩进模式下换行
+func (s *encoderStream) elemSep(i int) {
+	if s.w != nil && len(s.buffer) >= streamFlushThreshold {
+		s.flush()
+	}
+	if i > 0 {
+		s.buffer = append(s.buffer, ',')
+	}
+	if s.indenting {
+		s.writeNewline()
+	}
+}
+
+// flush 把缓冲区写出到 w 并清空；写入失败后不再写出，剩余输出直接丢弃
+func (s *encoderStream) flush() {
+	if s.werr == nil {
+		_, s.werr = s.w.Write(s.buffer)
+	}
+	s.buffer = s.buffer[:0]
+}
+
+// closeComposite 写入 '}' 或 ']'；与 encoding/json 一致，空对象/空数组不换行
+func (s *encoderStream) closeComposite(c byte, hasElems bool) {
+	if s.indenting {
+		s.depth--
+		if hasElems {
+			s.writeNewline()
+		}
+	}
+	s.buffer = append(s.buffer, c)
+}
+
+// writeKey 写入预计算的 `"name":`，缩进模式下冒号后加空格
+func (s *encoderStream) writeKey(key []byte) {
+	s.buffer = append(s.buffer, key...)
+	if s.indenting {
+		s.buffer = append(s.buffer, ' ')
+	}
+}
+
+// writeNewline 换行并写入前缀和 depth 层缩进
+func (s *encoderStream) writeNewline() {
+	s.buffer = append(s.buffer, '\n')
+	s.buffer = append(s.buffer, s.prefix...)
+	for i := 0; i < s.depth; i++ {
+		s.buffer = append(s.buffer, s.indent...)
+	}
+}
+
+// 获取一个使用 api 配置和缓存的编码器流
+func getEncoderStream(api *API) *encoderStream {
+	stream := encoderStreamPool.Get().(*encoderStream)
+	stream.config = api.config
+	stream.escape = api.escape
+	stream.codecs = api.codecs
+	return stream
 }
 
 // 释放一个编码器流
 func releaseEncoderStream(stream *encoderStream) {
+	stream.ptrLevel = 0
+	stream.indenting = false
+	stream.prefix, stream.indent = "", ""
+	stream.depth = 0
+	stream.w, stream.werr = nil, nil
+	stream.config = Config{}
+	stream.codecs = nil
+	for k := range stream.ptrSeen {
+		delete(stream.ptrSeen, k)
+	}
 	// 如果缓冲区过大，重新分配以避免内存泄漏
 	if cap(stream.buffer) > 65536 {
 		stream.buffer = make([]byte, 0, 4096)
@@ -64,8 +219,8 @@ func estimateJSONSize(v interface{}) int {
 }
 
 // 获取带预估大小的编码器流
-func getEncoderStreamWithSize(estimatedSize int) *encoderStream {
-	stream := encoderStreamPool.Get().(*encoderStream)
+func getEncoderStreamWithSize(estimatedSize int, api *API) *encoderStream {
+	stream := getEncoderStream(api)
 	if cap(stream.buffer) < estimatedSize {
 		stream.buffer = make([]byte, 0, estimatedSize)
 	}
@@ -74,59 +229,42 @@ func getEncoderStreamWithSize(estimatedSize int) *encoderStream {
 
 // Marshal 使用直接编码模式将Go对象编码为JSON字节切片
 func Marshal(v interface{}) ([]byte, error) {
-	// 估算所需缓冲区大小并获取编码器流
-	estimatedSize := estimateJSONSize(v)
-	stream := getEncoderStreamWithSize(estimatedSize)
-
-	// 保存编码后的结果
-	err := encodeValueToBytes(stream, reflect.ValueOf(v), reflect.TypeOf(v))
-	if err != nil {
-		releaseEncoderStream(stream)
-		return nil, err
-	}
-
-	// 复制结果（避免返回池中的缓冲区）
-	result := make([]byte, len(stream.buffer))
-	copy(result, stream.buffer)
-	releaseEncoderStream(stream)
+	return loadDefaultAPI().Marshal(v)
+}
 
-	return result, nil
+// MarshalWithConfig 使用指定配置将Go对象编码为JSON字节切片。
+// 配置随本次调用传递，不读取也不修改全局默认配置，可以在多个 goroutine 中以不同配置并发调用
+func MarshalWithConfig(v interface{}, config Config) ([]byte, error) {
+	api := apiWithConfig(config)
+	return api.Marshal(v)
 }
 
 // AppendMarshal 将 v 编码为 JSON 并追加到 dst 后面，返回追加后的切片。
 // 允许调用方复用自己的缓冲区，避免 Marshal 每次固定的 make+copy 分配
 // （对应 OPTIMIZATION_REVIEW.md §1.4 提到的"编码侧唯一剩下的分配来源"）。
 func AppendMarshal(dst []byte, v interface{}) ([]byte, error) {
-	estimatedSize := estimateJSONSize(v)
-	stream := getEncoderStreamWithSize(estimatedSize)
+	return loadDefaultAPI().AppendMarshal(dst, v)
+}
 
-	err := encodeValueToBytes(stream, reflect.ValueOf(v), reflect.TypeOf(v))
-	if err != nil {
-		releaseEncoderStream(stream)
-		return dst, err
-	}
+// AppendMarshalWithConfig 与 AppendMarshal 相同，但使用指定配置
+func AppendMarshalWithConfig(dst []byte, v interface{}, config Config) ([]byte, error) {
+	api := apiWithConfig(config)
+	return api.AppendMarshal(dst, v)
+}
 
-	dst = append(dst, stream.buffer...)
-	releaseEncoderStream(stream)
-	return dst, nil
+// MarshalIndent 与 Marshal 相同，但输出带缩进：每个元素另起一行，
+// 行首为 prefix，之后按嵌套层数重复 indent。结果与 encoding/json.MarshalIndent 一致。
+func MarshalIndent(v interface{}, prefix, indent string) ([]byte, error) {
+	return loadDefaultAPI().MarshalIndent(v, prefix, indent)
 }
 
 // MarshalString 使用直接编码模式将Go对象编码为JSON字符串
 func MarshalString(v interface{}) (string, error) {
-	// 估算所需缓冲区大小并获取编码器流
-	estimatedSize := estimateJSONSize(v)
-	stream := getEncoderStreamWithSize(estimatedSize)
-
-	// 保存编码后的结果
-	err := encodeValueToBytes(stream, reflect.ValueOf(v), reflect.TypeOf(v))
-	if err != nil {
-		releaseEncoderStream(stream)
-		return "", err
-	}
-
-	// 直接转换为字符串（零拷贝，但需要复制以避免使用池中的缓冲区）
-	result := string(stream.buffer)
-	releaseEncoderStream(stream)
+	return loadDefaultAPI().MarshalString(v)
+}
 
-	return result, nil
+// MarshalStringWithConfig 与 MarshalString 相同，但使用指定配置
+func MarshalStringWithConfig(v interface{}, config Config) (string, error) {
+	api := apiWithConfig(config)
+	return api.MarshalString(v)
 }
@@ -0,0 +1,95 @@
+package sjson
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// FieldNamer 把 Go 字段名转换为 JSON 键名，用于 Config.FieldNamer。
+// 编解码器缓存按 FieldNamer 指针区分，自定义的 FieldNamer 应创建一次后复用
+type FieldNamer struct {
+	fn func(string) string
+}
+
+// NewFieldNamer 用自定义函数创建 FieldNamer，fn 接收 Go 字段名，返回 JSON 键名
+func NewFieldNamer(fn func(goName string) string) *FieldNamer {
+	return &FieldNamer{fn: fn}
+}
+
+// Name 返回 Go 字段名对应的 JSON 键名
+func (n *FieldNamer) Name(goName string) string {
+	return n.fn(goName)
+}
+
+// 内置的命名策略。单词按大小写变化与下划线切分，连续的大写字母视为一个缩写词：
+// UserID → user_id，HTTPServer → http_server，Address2 → address2
+var (
+	// SnakeCase 小写单词以下划线连接：UserID → user_id
+	SnakeCase = NewFieldNamer(func(name string) string { return joinLower(name, '_') })
+
+	// KebabCase 小写单词以连字符连接：UserID → user-id
+	KebabCase = NewFieldNamer(func(name string) string { return joinLower(name, '-') })
+
+	// LowerCamel 首个单词小写，其余单词首字母大写：UserID → userID，HTTPServer → httpServer
+	LowerCamel = NewFieldNamer(func(name string) string { return joinCamel(name, false) })
+
+	// UpperCamel 每个单词首字母大写：user_name → UserName
+	UpperCamel = NewFieldNamer(func(name string) string { return joinCamel(name, true) })
+)
+
+// splitWords 把标识符切分为单词：下划线、连字符是分隔符；小写字母或数字后的大写字母开始新单词；
+// 连续大写字母中，后面紧跟小写字母的最后一个大写字母开始新单词
+func splitWords(name string) []string {
+	var words []string
+	runes := []rune(name)
+	start := 0
+	for i, r := range runes {
+		if r == '_' || r == '-' {
+			if i > start {
+				words = append(words, string(runes[start:i]))
+			}
+			start = i + 1
+			continue
+		}
+		if i == start || !unicode.IsUpper(r) {
+			continue
+		}
+		prev := runes[i-1]
+		if unicode.IsLower(prev) || unicode.IsDigit(prev) ||
+			(unicode.IsUpper(prev) && i+1 < len(runes) && unicode.IsLower(runes[i+1])) {
+			words = append(words, string(runes[start:i]))
+			start = i
+		}
+	}
+	if start < len(runes) {
+		words = append(words, string(runes[start:]))
+	}
+	return words
+}
+
+func joinLower(name string, sep byte) string {
+	var b strings.Builder
+	for i, w := range splitWords(name) {
+		if i > 0 {
+			b.WriteByte(sep)
+		}
+		b.WriteString(strings.ToLower(w))
+	}
+	return b.String()
+}
+
+// joinCamel 连接单词：除 LowerCamel 的首个单词整体小写外，每个单词只把首字母改为大写，其余保持原样
+func joinCamel(name string, upperFirst bool) string {
+	var b strings.Builder
+	for i, w := range splitWords(name) {
+		if i == 0 && !upperFirst {
+			b.WriteString(strings.ToLower(w))
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(w)
+		b.WriteRune(unicode.ToUpper(r))
+		b.WriteString(w[size:])
+	}
+	return b.String()
+}
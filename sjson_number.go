@@ -0,0 +1,125 @@
+package sjson
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+// Number 是 JSON 数字的原始文本，对应 encoding/json.Number。
+// 解码时保留记号的原始字节，不损失精度；编码时原样写出
+type Number string
+
+// String 返回数字的原始文本
+func (n Number) String() string { return string(n) }
+
+// Float64 将数字解析为 float64
+func (n Number) Float64() (float64, error) {
+	return strconv.ParseFloat(string(n), 64)
+}
+
+// Int64 将数字解析为 int64，不是整数或超出范围时返回错误
+func (n Number) Int64() (int64, error) {
+	return strconv.ParseInt(string(n), 10, 64)
+}
+
+// NumberMode 指定解码到 interface{} 时 JSON 数字的表示方式，
+// 对 interface{}、[]interface{}、map[string]interface{} 等所有路径一致生效
+type NumberMode int
+
+const (
+	// NumberAsFloat64 解码为 float64（默认，与 encoding/json 一致），超过 2^53 的整数会丢失精度
+	NumberAsFloat64 NumberMode = iota
+	// NumberAsNumber 解码为 Number，保留原始文本（相当于 encoding/json 的 UseNumber）
+	NumberAsNumber
+	// NumberAsInt64WhenIntegral 不带小数点与指数、且在 int64 范围内的整数解码为 int64，其余解码为 float64
+	NumberAsInt64WhenIntegral
+)
+
+var (
+	numberType     = reflect.TypeOf(Number(""))
+	jsonNumberType = reflect.TypeOf(json.Number(""))
+)
+
+// isNumberType 报告 t 是否为 Number 或 encoding/json.Number，两者按数字文本编解码
+func isNumberType(t reflect.Type) bool {
+	return t == numberType || t == jsonNumberType
+}
+
+// numberInterface 按 NumberMode 返回数字在 interface{} 中的表示，参数取自数字记号
+func (d *Decoder) numberInterface(value float64, intValue int64, raw []byte, isInteger bool) interface{} {
+	switch d.config.NumberMode {
+	case NumberAsNumber:
+		return Number(d.copyString(raw))
+	case NumberAsInt64WhenIntegral:
+		if isInteger {
+			return intValue
+		}
+	}
+	return value
+}
+
+// numberEncoder 编码 Number / json.Number：空串写为 0，其余必须是合法的 JSON 数字
+type numberEncoder struct{}
+
+func (numberEncoder) appendToBytes(stream *encoderStream, src reflect.Value) error {
+	s := src.String()
+	if s == "" {
+		s = "0"
+	}
+	if !isValidNumber(s) {
+		return fmt.Errorf("json: invalid number literal %q", s)
+	}
+	stream.buffer = append(stream.buffer, s...)
+	return nil
+}
+
+// isValidNumber 报告 s 是否符合 JSON 数字语法
+func isValidNumber(s string) bool {
+	if s == "" {
+		return false
+	}
+	if s[0] == '-' {
+		s = s[1:]
+		if s == "" {
+			return false
+		}
+	}
+
+	// 整数部分：单个 0，或不以 0 开头的数字串
+	switch {
+	case s[0] == '0':
+		s = s[1:]
+	case '1' <= s[0] && s[0] <= '9':
+		s = s[1:]
+		for len(s) > 0 && '0' <= s[0] && s[0] <= '9' {
+			s = s[1:]
+		}
+	default:
+		return false
+	}
+
+	// 小数部分
+	if len(s) >= 2 && s[0] == '.' && '0' <= s[1] && s[1] <= '9' {
+		s = s[2:]
+		for len(s) > 0 && '0' <= s[0] && s[0] <= '9' {
+			s = s[1:]
+		}
+	}
+
+	// 指数部分
+	if len(s) >= 2 && (s[0] == 'e' || s[0] == 'E') {
+		s = s[1:]
+		if s[0] == '+' || s[0] == '-' {
+			s = s[1:]
+			if s == "" {
+				return false
+			}
+		}
+		for len(s) > 0 && '0' <= s[0] && s[0] <= '9' {
+			s = s[1:]
+		}
+	}
+	return s == ""
+}
@@ -2,56 +2,170 @@ package sjson
 
 import (
 	"reflect"
-	"sync"
 	"unsafe"
 )
 
-// OPT-8: 结构体编码的运行时 opcode 程序。仅处理无 omitempty 的标量字段；
-// 复杂字段保留原有 Encoder 回退，保证与 encoding/json 的兼容语义。
+// OPT-8: 结构体编码的运行时 opcode 程序。每个字段编译为一条指令，按预计算的偏移量直接读内存：
+// 标量直接写出；嵌套结构体执行其自身的程序；指针、切片、数组通过 elem 指令逐层展开；
+// map 与 Marshaler、注册的编码函数、",string"、interface 等字段通过 opCall 调用字段编码器，
+// 保证与 encoding/json 的兼容语义。
 type structOpcode byte
 
 const (
-	opFallback structOpcode = iota
+	opCall structOpcode = iota // 调用编码器（按地址构造 reflect.Value）
 	opBool
 	opInt
 	opUint
 	opFloat32
 	opFloat64
 	opString
+	opStruct   // 嵌套结构体：在字段地址上执行其 opcode 程序
+	opPtr      // 指针：nil 输出 null，否则在指向的地址上执行 elem
+	opSlice    // []T：按元素大小 stride 循环执行 elem
+	opArray    // [N]T：同 opSlice，长度固定
+	opMap      // map：nil 直接输出 null，否则调用 map 编码器
+	opIndirect // 经由嵌入指针提升的字段：没有偏移量，通过 reflect 逐级解引用
 )
 
+// omitOpcode 是字段的 omitempty / omitzero 判断方式，编译时按字段类型选定
+type omitOpcode byte
+
+const (
+	opOmitNever       omitOpcode = iota
+	opOmitEmptyScalar            // 标量为零（对标量 omitempty 与 omitzero 等价）
+	opOmitEmptyNil               // 指针为 nil，或 omitzero 的切片为 nil
+	opOmitEmptyLen               // omitempty 的切片长度为 0
+	opOmitReflect                // 其余情况按 structField.omitted 判断
+)
+
+// opcodeInstr 是一个值的编码指令。指针、切片、数组的元素指令由 elem 指向
+type opcodeInstr struct {
+	op      structOpcode
+	omit    omitOpcode   // 仅结构体字段的指令使用
+	kind    reflect.Kind // 标量的具体宽度
+	typ     reflect.Type
+	encoder Encoder // opCall / opMap / opStruct 使用
+	elem    *opcodeInstr
+	stride  uintptr // 元素大小
+	length  int     // 数组长度
+}
+
 type structOpcodeProgram struct {
-	ops      []structOpcode
+	typ      reflect.Type
+	ops      []opcodeInstr
 	shapeSig uint64
-	valid    bool
 }
 
-// OPT-7: ShapeSig 缓存相同字段形状的 opcode 程序，避免重复分类。
-var shapeProgramCache sync.Map // map[reflect.Type]*structOpcodeProgram
-
-func newStructOpcodeProgram(t reflect.Type, fields []structField) *structOpcodeProgram {
+// OPT-7: ShapeSig 缓存相同字段形状的 opcode 程序（codecCache.programs），避免重复分类。
+func (c *codecCache) newStructOpcodeProgram(t reflect.Type, fields []structField) *structOpcodeProgram {
 	sig := shapeSignature(fields)
-	if cached, ok := shapeProgramCache.Load(t); ok {
+	if cached, ok := c.programs.Load(t); ok {
 		return cached.(*structOpcodeProgram)
 	}
 
 	program := &structOpcodeProgram{
-		ops:      make([]structOpcode, len(fields)),
+		typ:      t,
+		ops:      make([]opcodeInstr, len(fields)),
 		shapeSig: sig,
 	}
-	program.valid = true
-	for i, field := range fields {
-		program.ops[i] = opcodeForType(field.typ)
-		if program.ops[i] == opFallback {
-			program.valid = false
+	for i := range fields {
+		field := &fields[i]
+		in := &program.ops[i]
+		switch {
+		case field.indirect:
+			in.op = opIndirect
+		case field.asString:
+			in.op, in.typ, in.encoder = opCall, field.typ, field.encoder
+		default:
+			*in = *c.compileOpcode(field.typ, nil)
 		}
+		in.omit = omitOpcodeFor(field, in)
 	}
-	actual, _ := shapeProgramCache.LoadOrStore(t, program)
+	actual, _ := c.programs.LoadOrStore(t, program)
 	return actual.(*structOpcodeProgram)
 }
 
+// compileOpcode 为类型 t 的值编译指令。compiling 记录正在编译 elem 的指针、切片、数组类型：
+// 递归类型（如 type Tree []Tree）再次遇到时复用同一条指令，指令成环，执行时随数据逐层展开
+func (c *codecCache) compileOpcode(t reflect.Type, compiling map[reflect.Type]*opcodeInstr) *opcodeInstr {
+	if in := compiling[t]; in != nil {
+		return in
+	}
+	in := &opcodeInstr{op: opcodeForType(t), kind: t.Kind(), typ: t}
+	if in.op != opCall {
+		if c.typeEncoder(t) != nil {
+			in.op, in.encoder = opCall, c.getEncoder(t)
+		}
+		return in
+	}
+	// 与 buildEncoder 一致：T 注册的编码函数优先于 *T 方法集中的 Marshaler
+	if t.Kind() == reflect.Ptr && c.typeEncoder(t) == nil && c.typeEncoder(t.Elem()) != nil {
+		in.op, in.elem = opPtr, c.compileOpcode(t.Elem(), compiling)
+		return in
+	}
+	if hasCustomEncoder(t) || c.typeEncoder(t) != nil {
+		in.encoder = c.getEncoder(t)
+		return in
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Array:
+		if compiling == nil {
+			compiling = make(map[reflect.Type]*opcodeInstr)
+		}
+		compiling[t] = in
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		in.op, in.encoder = opStruct, c.getEncoder(t)
+	case reflect.Ptr:
+		in.op, in.elem = opPtr, c.compileOpcode(t.Elem(), compiling)
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			// []byte 按 base64 字符串编码
+			in.encoder = c.getEncoder(t)
+			break
+		}
+		in.op, in.elem, in.stride = opSlice, c.compileOpcode(t.Elem(), compiling), t.Elem().Size()
+	case reflect.Array:
+		in.op, in.elem, in.stride, in.length = opArray, c.compileOpcode(t.Elem(), compiling), t.Elem().Size(), t.Len()
+	case reflect.Map:
+		in.op, in.encoder = opMap, c.getEncoder(t)
+	default:
+		in.encoder = c.getEncoder(t)
+	}
+	return in
+}
+
+func omitOpcodeFor(field *structField, in *opcodeInstr) omitOpcode {
+	if !field.omitempty && !field.omitzero {
+		return opOmitNever
+	}
+	if field.isZero != nil || in.op == opIndirect {
+		return opOmitReflect
+	}
+	switch in.op {
+	case opBool, opInt, opUint, opFloat32, opFloat64, opString:
+		return opOmitEmptyScalar
+	case opPtr:
+		return opOmitEmptyNil
+	case opStruct:
+		// 结构体不会因 omitempty 省略（与 encoding/json 一致）
+		if !field.omitzero {
+			return opOmitNever
+		}
+	case opSlice:
+		if field.omitempty {
+			return opOmitEmptyLen
+		}
+		return opOmitEmptyNil
+	}
+	return opOmitReflect
+}
+
 func shapeSignature(fields []structField) uint64 {
-	// FNV-1a；包含名称、类型和 omitempty，避免不同 JSON 语义共享程序。
+	// FNV-1a；包含名称、类型、omitempty、omitzero 和 string 选项，避免不同 JSON 语义共享程序。
 	var h uint64 = 1469598103934665603
 	for _, field := range fields {
 		for _, c := range field.name {
@@ -64,23 +178,42 @@ func shapeSignature(fields []structField) uint64 {
 			h ^= 1
 			h *= 1099511628211
 		}
+		if field.asString {
+			h ^= 2
+			h *= 1099511628211
+		}
+		if field.indirect {
+			h ^= 4
+			h *= 1099511628211
+		}
+		if field.omitzero {
+			h ^= 8
+			h *= 1099511628211
+		}
 	}
 	return h
 }
 
+// hasCustomEncoder 报告 t 或 *t 是否实现了 json.Marshaler / encoding.TextMarshaler
+func hasCustomEncoder(t reflect.Type) bool {
+	if t.Implements(jsonMarshalerType) || t.Implements(textMarshalerType) {
+		return true
+	}
+	if t.Kind() != reflect.Ptr {
+		pt := reflect.PointerTo(t)
+		return pt.Implements(jsonMarshalerType) || pt.Implements(textMarshalerType)
+	}
+	return false
+}
+
+// opcodeForType 返回标量类型的指令，其余类型返回 opCall
 func opcodeForType(t reflect.Type) structOpcode {
 	// 关键修复：具名类型（如 type Celsius float64）即使 Kind() 是标量，
 	// 也可能实现了 json.Marshaler / encoding.TextMarshaler，必须调用其
 	// 自定义方法而不能按裸类型直写内存，否则会产出与 encoding/json 不一致的结果。
 	// （§1.7 指出的"ShapeSig 只能表达形状、无法表达真实类型"问题在此处的具体体现）
-	if t.Implements(jsonMarshalerType) || t.Implements(textMarshalerType) {
-		return opFallback
-	}
-	if t.Kind() != reflect.Ptr {
-		pt := reflect.PointerTo(t)
-		if pt.Implements(jsonMarshalerType) || pt.Implements(textMarshalerType) {
-			return opFallback
-		}
+	if hasCustomEncoder(t) || isNumberType(t) {
+		return opCall
 	}
 	switch t.Kind() {
 	case reflect.Bool:
@@ -96,48 +229,195 @@ func opcodeForType(t reflect.Type) structOpcode {
 	case reflect.String:
 		return opString
 	default:
-		return opFallback
+		return opCall
 	}
 }
 
-// appendToBytes 执行预编译 opcode。调用方只对 valid 程序调用本函数。
-func (p *structOpcodeProgram) appendToBytes(stream *encoderStream, base unsafe.Pointer, fields []structField) error {
+// appendToBytes 执行预编译 opcode，base 为结构体的地址（调用方已写入 '{'）。
+// src 为同一结构体的 reflect.Value，opCall 字段用它取字段值；
+// 嵌套执行时为无效值，需要时才按地址构造
+func (p *structOpcodeProgram) appendToBytes(stream *encoderStream, base unsafe.Pointer, fields []structField, src reflect.Value) error {
+	written := 0
 	for i := range fields {
-		if i > 0 {
-			stream.buffer = append(stream.buffer, ',')
-		}
 		field := &fields[i]
-		stream.buffer = append(stream.buffer, field.keyBytes...)
-		ptr := unsafe.Add(base, field.offset)
+		in := &p.ops[i]
 
-		switch p.ops[i] {
-		case opBool:
-			if *(*bool)(ptr) {
-				stream.buffer = append(stream.buffer, trueString...)
-			} else {
-				stream.buffer = append(stream.buffer, falseString...)
+		if in.op == opIndirect {
+			if !src.IsValid() {
+				src = reflect.NewAt(p.typ, base).Elem()
 			}
-		case opInt:
-			stream.buffer = appendInt(stream.buffer, readInt(ptr, field.typ.Kind()), 10)
-		case opUint:
-			stream.buffer = appendUint(stream.buffer, readUint(ptr, field.typ.Kind()), 10)
-		case opFloat32:
-			if err := appendFloat32(stream, *(*float32)(ptr)); err != nil {
-				return err
+			v, ok := structFieldValue(src, field)
+			if !ok || field.omitted(v) {
+				continue
 			}
-		case opFloat64:
-			if err := appendFloat64(stream, *(*float64)(ptr)); err != nil {
-				return err
+			stream.elemSep(written)
+			written++
+			stream.writeKey(field.key(stream.escape))
+			if err := field.encoder.appendToBytes(stream, v); err != nil {
+				return withFieldPath(err, bytesToString(field.name))
 			}
-		case opString:
-			if err := encodeStringDirect(stream, *(*string)(ptr)); err != nil {
-				return err
+			continue
+		}
+
+		ptr := unsafe.Add(base, field.offset)
+		if in.omit != opOmitNever && in.omitted(ptr, field) {
+			continue
+		}
+		stream.elemSep(written)
+		written++
+		stream.writeKey(field.key(stream.escape))
+
+		var err error
+		if in.op == opCall {
+			if !src.IsValid() {
+				src = reflect.NewAt(p.typ, base).Elem()
 			}
-		default:
-			panic("sjson: invalid struct opcode program")
+			err = in.encoder.appendToBytes(stream, fieldByIndex(src, field.index))
+		} else {
+			err = in.appendToBytes(stream, ptr)
+		}
+		if err != nil {
+			return withFieldPath(err, bytesToString(field.name))
 		}
 	}
-	stream.buffer = append(stream.buffer, '}')
+	stream.closeComposite('}', written > 0)
+	return nil
+}
+
+// omitted 直接读内存判断字段是否因 omitempty / omitzero 省略
+func (in *opcodeInstr) omitted(ptr unsafe.Pointer, field *structField) bool {
+	switch in.omit {
+	case opOmitEmptyScalar:
+		return scalarIsZero(in.op, in.kind, ptr)
+	case opOmitEmptyNil:
+		return *(*unsafe.Pointer)(ptr) == nil
+	case opOmitEmptyLen:
+		return (*sliceHeader)(ptr).len == 0
+	case opOmitReflect:
+		return field.omitted(reflect.NewAt(in.typ, ptr).Elem())
+	}
+	return false
+}
+
+// scalarIsZero 报告标量是否为零值。对标量 omitempty 与 omitzero 等价
+// （reflect.Value.IsZero 同样把 -0 视为零值）
+func scalarIsZero(op structOpcode, kind reflect.Kind, ptr unsafe.Pointer) bool {
+	switch op {
+	case opBool:
+		return !*(*bool)(ptr)
+	case opInt:
+		return readInt(ptr, kind) == 0
+	case opUint:
+		return readUint(ptr, kind) == 0
+	case opFloat32:
+		return *(*float32)(ptr) == 0
+	case opFloat64:
+		return *(*float64)(ptr) == 0
+	case opString:
+		return len(*(*string)(ptr)) == 0
+	}
+	return false
+}
+
+// sliceHeader 与切片的内存布局一致
+type sliceHeader struct {
+	data unsafe.Pointer
+	len  int
+	cap  int
+}
+
+// appendToBytes 编码位于 ptr 的值
+func (in *opcodeInstr) appendToBytes(stream *encoderStream, ptr unsafe.Pointer) error {
+	switch in.op {
+	case opBool:
+		if *(*bool)(ptr) {
+			stream.buffer = append(stream.buffer, trueString...)
+		} else {
+			stream.buffer = append(stream.buffer, falseString...)
+		}
+	case opInt:
+		stream.buffer = appendInt(stream.buffer, readInt(ptr, in.kind), 10)
+	case opUint:
+		stream.buffer = appendUint(stream.buffer, readUint(ptr, in.kind), 10)
+	case opFloat32:
+		return appendFloat32(stream, *(*float32)(ptr))
+	case opFloat64:
+		return appendFloat64(stream, *(*float64)(ptr))
+	case opString:
+		return encodeStringDirect(stream, *(*string)(ptr))
+	case opStruct:
+		if se := structEncoderOf(in.encoder); se != nil {
+			stream.openComposite('{')
+			return se.opcodes.appendToBytes(stream, ptr, se.fields, reflect.Value{})
+		}
+		return in.encoder.appendToBytes(stream, reflect.NewAt(in.typ, ptr).Elem())
+	case opPtr:
+		p := *(*unsafe.Pointer)(ptr)
+		if p == nil {
+			stream.buffer = append(stream.buffer, nullString...)
+			return nil
+		}
+		if err := stream.enterRefAt(in.typ, ptr); err != nil {
+			return err
+		}
+		err := in.elem.appendToBytes(stream, p)
+		stream.leaveRefAt(in.typ, ptr)
+		return err
+	case opSlice:
+		s := (*sliceHeader)(ptr)
+		if s.data == nil {
+			stream.buffer = append(stream.buffer, nullString...)
+			return nil
+		}
+		if err := stream.enterRefAt(in.typ, ptr); err != nil {
+			return err
+		}
+		err := in.appendElems(stream, s.data, s.len)
+		stream.leaveRefAt(in.typ, ptr)
+		return err
+	case opArray:
+		return in.appendElems(stream, ptr, in.length)
+	case opMap:
+		if *(*unsafe.Pointer)(ptr) == nil {
+			stream.buffer = append(stream.buffer, nullString...)
+			return nil
+		}
+		return in.encoder.appendToBytes(stream, reflect.NewAt(in.typ, ptr).Elem())
+	case opCall:
+		return in.encoder.appendToBytes(stream, reflect.NewAt(in.typ, ptr).Elem())
+	default:
+		panic("sjson: invalid struct opcode program")
+	}
+	return nil
+}
+
+// appendElems 编码从 data 开始、间隔 stride 的 n 个元素
+func (in *opcodeInstr) appendElems(stream *encoderStream, data unsafe.Pointer, n int) error {
+	if n == 0 {
+		stream.buffer = append(stream.buffer, emptyArray...)
+		return nil
+	}
+	stream.openComposite('[')
+	for i := 0; i < n; i++ {
+		stream.elemSep(i)
+		if err := in.elem.appendToBytes(stream, unsafe.Add(data, uintptr(i)*in.stride)); err != nil {
+			return withIndexPath(err, i)
+		}
+	}
+	stream.closeComposite(']', true)
+	return nil
+}
+
+// structEncoderOf 取嵌套结构体的编码器；递归类型构建期间拿到的是占位的 indirectEncoder
+func structEncoderOf(enc Encoder) *structEncoder {
+	switch e := enc.(type) {
+	case *structEncoder:
+		return e
+	case *indirectEncoder:
+		e.wg.Wait()
+		se, _ := e.enc.(*structEncoder)
+		return se
+	}
 	return nil
 }
 
@@ -0,0 +1,265 @@
+package sjson
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// TypeEncoderFunc 是为某个类型注册的编码函数：v 为待编码的值（其动态类型即注册的类型），
+// 通过 w 写出恰好一个 JSON 值
+type TypeEncoderFunc func(v interface{}, w *Writer) error
+
+// TypeDecoderFunc 是为某个类型注册的解码函数：ptr 为指向目标的指针（*T），
+// 通过 r 读取恰好一个 JSON 值
+type TypeDecoderFunc func(ptr interface{}, r *Reader) error
+
+// RegisterTypeEncoder 为类型 t 注册全局编码函数，对包级函数和所有 API 生效，
+// 优先级高于 json.Marshaler / encoding.TextMarshaler。用于无法为其添加 MarshalJSON 的第三方类型
+// （如 uuid.UUID、decimal.Decimal）。t 必须是具名类型或指向具名类型的指针；
+// 应在初始化阶段、开始编码之前完成注册。map 的键不受影响
+func RegisterTypeEncoder(t reflect.Type, fn TypeEncoderFunc) {
+	defaultCodecs.registerEncoder(t, fn)
+}
+
+// RegisterTypeDecoder 为类型 t 注册全局解码函数，对包级函数和所有 API 生效，
+// 优先级高于 json.Unmarshaler / encoding.TextUnmarshaler。约束同 RegisterTypeEncoder
+func RegisterTypeDecoder(t reflect.Type, fn TypeDecoderFunc) {
+	defaultCodecs.registerDecoder(t, fn)
+}
+
+// RegisterTypeEncoder 为类型 t 注册只在该 API 内生效的编码函数，优先于全局注册的函数
+func (a *API) RegisterTypeEncoder(t reflect.Type, fn TypeEncoderFunc) {
+	a.codecs.registerEncoder(t, fn)
+}
+
+// RegisterTypeDecoder 为类型 t 注册只在该 API 内生效的解码函数，优先于全局注册的函数
+func (a *API) RegisterTypeDecoder(t reflect.Type, fn TypeDecoderFunc) {
+	a.codecs.registerDecoder(t, fn)
+}
+
+func checkRegisterType(name string, t reflect.Type) {
+	named := t
+	if named.Kind() == reflect.Ptr {
+		named = named.Elem()
+	}
+	// 未命名类型与内置类型（int、[]byte 等）不能注册，否则会绕开快速路径的类型判断
+	if named.PkgPath() == "" {
+		panic("sjson: " + name + " requires a named type or a pointer to one, got " + t.String())
+	}
+}
+
+func (c *codecCache) registerEncoder(t reflect.Type, fn TypeEncoderFunc) {
+	checkRegisterType("RegisterTypeEncoder", t)
+	c.typeEncoders.Store(t, fn)
+	c.reset()
+}
+
+func (c *codecCache) registerDecoder(t reflect.Type, fn TypeDecoderFunc) {
+	checkRegisterType("RegisterTypeDecoder", t)
+	c.typeDecoders.Store(t, fn)
+	c.hasTypeDecoders.Store(true)
+	c.reset()
+}
+
+// reset 丢弃本缓存中已编译的编解码器与字段信息，使新注册的函数对之后的编解码生效。
+// 全局注册同时清空各字段命名方式的共享缓存；已经编解码过该类型的 API 不受影响，
+// 因此全局注册应在开始编解码之前完成
+func (c *codecCache) reset() {
+	clearSyncMap(c.encoders)
+	clearSyncMap(&c.structFields)
+	clearSyncMap(&c.programs)
+	clearSyncMap(&c.decPrograms)
+	if c == defaultCodecs {
+		namedCodecs.Range(func(_, v interface{}) bool {
+			v.(*codecCache).reset()
+			return true
+		})
+	}
+}
+
+func clearSyncMap(m *sync.Map) {
+	m.Range(func(k, _ interface{}) bool {
+		m.Delete(k)
+		return true
+	})
+}
+
+// typeEncoder 返回 t 的注册编码函数，先查本缓存，再查全局注册
+func (c *codecCache) typeEncoder(t reflect.Type) TypeEncoderFunc {
+	if fn, ok := c.typeEncoders.Load(t); ok {
+		return fn.(TypeEncoderFunc)
+	}
+	if c != defaultCodecs {
+		if fn, ok := defaultCodecs.typeEncoders.Load(t); ok {
+			return fn.(TypeEncoderFunc)
+		}
+	}
+	return nil
+}
+
+// typeDecoder 返回 t 的注册解码函数。解码的每个值都会调用，未注册任何解码函数时只有两次原子读
+func (c *codecCache) typeDecoder(t reflect.Type) TypeDecoderFunc {
+	if c.hasTypeDecoders.Load() {
+		if fn, ok := c.typeDecoders.Load(t); ok {
+			return fn.(TypeDecoderFunc)
+		}
+	}
+	if c != defaultCodecs && defaultCodecs.hasTypeDecoders.Load() {
+		if fn, ok := defaultCodecs.typeDecoders.Load(t); ok {
+			return fn.(TypeDecoderFunc)
+		}
+	}
+	return nil
+}
+
+// typeFuncEncoder 调用注册的编码函数
+type typeFuncEncoder struct{ fn TypeEncoderFunc }
+
+func (e typeFuncEncoder) appendToBytes(stream *encoderStream, src reflect.Value) error {
+	if src.Kind() == reflect.Ptr && src.IsNil() {
+		stream.buffer = append(stream.buffer, nullString...)
+		return nil
+	}
+	return e.fn(src.Interface(), (*Writer)(stream))
+}
+
+// callTypeDecoder 以 dst 的地址调用注册的解码函数，并确认它读取了当前值
+func (d *Decoder) callTypeDecoder(fn TypeDecoderFunc, dst reflect.Value) error {
+	typ, pos := d.token.Type, d.lexer.offset+int64(d.token.Pos)
+	if err := fn(dst.Addr().Interface(), (*Reader)(d)); err != nil {
+		return err
+	}
+	if d.token.Type == typ && d.lexer.offset+int64(d.token.Pos) == pos {
+		return fmt.Errorf("类型 %v 的解码函数没有读取任何值", dst.Type())
+	}
+	return nil
+}
+
+// Writer 是传给 TypeEncoderFunc 的输出端，写入的内容直接追加到当前编码结果中。
+// 只在编码函数执行期间有效，不能保存到之后使用
+type Writer encoderStream
+
+func (w *Writer) stream() *encoderStream {
+	return (*encoderStream)(w)
+}
+
+// WriteRaw 写入一段完整的 JSON 文本，处理方式与 MarshalJSON 的返回值相同：
+// 缩进模式下重新缩进，开启 HTML / 行终止符转义时补充转义。默认不做校验，由调用方保证合法
+func (w *Writer) WriteRaw(data []byte) error {
+	return appendRawJSON(w.stream(), data)
+}
+
+// WriteNull 写入 null
+func (w *Writer) WriteNull() {
+	w.buffer = append(w.buffer, nullString...)
+}
+
+// WriteBool 写入布尔值
+func (w *Writer) WriteBool(b bool) {
+	if b {
+		w.buffer = append(w.buffer, trueString...)
+	} else {
+		w.buffer = append(w.buffer, falseString...)
+	}
+}
+
+// WriteInt 写入有符号整数
+func (w *Writer) WriteInt(n int64) {
+	w.buffer = appendInt(w.buffer, n, 10)
+}
+
+// WriteUint 写入无符号整数
+func (w *Writer) WriteUint(n uint64) {
+	w.buffer = appendUint(w.buffer, n, 10)
+}
+
+// WriteFloat64 按当前配置写入浮点数，NaN/±Inf 的处理同 Config.NonFiniteFloats
+func (w *Writer) WriteFloat64(f float64) error {
+	return appendFloat64(w.stream(), f)
+}
+
+// WriteString 按当前配置转义并写入字符串
+func (w *Writer) WriteString(s string) error {
+	return encodeStringDirect(w.stream(), s)
+}
+
+// WriteValue 按常规规则编码任意值。不要对正在编码的同一类型调用，否则会无限递归
+func (w *Writer) WriteValue(v interface{}) error {
+	if v == nil {
+		w.WriteNull()
+		return nil
+	}
+	return w.codecs.getEncoder(reflect.TypeOf(v)).appendToBytes(w.stream(), reflect.ValueOf(v))
+}
+
+// Reader 是传给 TypeDecoderFunc 的输入端。解码函数必须恰好读取一个值，
+// 且只在执行期间有效，不能保存到之后使用
+type Reader Decoder
+
+func (r *Reader) decoder() *Decoder {
+	return (*Decoder)(r)
+}
+
+// Peek 返回下一个值的标记类型，不消耗输入
+func (r *Reader) Peek() TokenType {
+	return r.token.Type
+}
+
+// ReadNull 在下一个值为 null 时读取它并返回 true，否则不消耗输入
+func (r *Reader) ReadNull() bool {
+	if r.token.Type != NullToken {
+		return false
+	}
+	r.decoder().nextToken()
+	return true
+}
+
+// ReadRaw 读取下一个值的原始 JSON 文本（返回副本）
+func (r *Reader) ReadRaw() ([]byte, error) {
+	return r.decoder().readRawValue()
+}
+
+// Skip 跳过下一个值
+func (r *Reader) Skip() error {
+	return r.decoder().skipValue()
+}
+
+// ReadString 读取字符串
+func (r *Reader) ReadString() (s string, err error) {
+	err = r.ReadValue(&s)
+	return
+}
+
+// ReadBool 读取布尔值
+func (r *Reader) ReadBool() (b bool, err error) {
+	err = r.ReadValue(&b)
+	return
+}
+
+// ReadInt 读取有符号整数
+func (r *Reader) ReadInt() (n int64, err error) {
+	err = r.ReadValue(&n)
+	return
+}
+
+// ReadUint 读取无符号整数
+func (r *Reader) ReadUint() (n uint64, err error) {
+	err = r.ReadValue(&n)
+	return
+}
+
+// ReadFloat64 读取浮点数
+func (r *Reader) ReadFloat64() (f float64, err error) {
+	err = r.ReadValue(&f)
+	return
+}
+
+// ReadValue 按常规规则把下一个值解码到 v（非 nil 指针）。不要对正在解码的同一类型调用，否则会无限递归
+func (r *Reader) ReadValue(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("解码目标必须是非nil指针")
+	}
+	return r.decoder().decodeValue(rv)
+}
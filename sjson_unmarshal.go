@@ -6,27 +6,24 @@ import (
 
 // Unmarshal 将JSON字节切片直接解码到Go对象
 func Unmarshal(data []byte, v interface{}) error {
-	return UnmarshalWithConfig(data, v, defaultConfig)
+	return loadDefaultAPI().Unmarshal(data, v)
 }
 
 // UnmarshalWithConfig 将JSON字节切片直接解码到Go对象，使用指定配置
 func UnmarshalWithConfig(data []byte, v interface{}, config Config) error {
-	decoder := newDecoder(data, config)
-	defer releaseDecoder(decoder)
-	return decoder.Decode(v)
+	api := apiWithConfig(config)
+	return api.Unmarshal(data, v)
 }
 
 // UnmarshalFromReader 从io.Reader读取JSON并直接解码到Go对象
 func UnmarshalFromReader(r io.Reader, v interface{}) error {
-	return UnmarshalFromReaderWithConfig(r, v, defaultConfig)
+	return loadDefaultAPI().UnmarshalFromReader(r, v)
 }
 
 // UnmarshalFromReaderWithConfig 从io.Reader读取JSON并直接解码到Go对象，使用指定配置
+//
+// 输入边读边解码，不会先把整个 r 读入内存；与 Unmarshal 一致，值之后只允许空白
 func UnmarshalFromReaderWithConfig(r io.Reader, v interface{}, config Config) error {
-	decoder, err := newDecoderFromReader(r, config)
-	if err != nil {
-		return err
-	}
-	defer releaseDecoder(decoder)
-	return decoder.Decode(v)
+	api := apiWithConfig(config)
+	return api.UnmarshalFromReader(r, v)
 }